@@ -9,9 +9,11 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // time zones of links, also on images without zoneinfo
 
 	"github.com/abhisheksharm-3/shrtn/internal/api"
 	"github.com/abhisheksharm-3/shrtn/internal/config"
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
 )
 
 func main() {
@@ -20,7 +22,18 @@ func main() {
 		log.Fatalf("failed to load configuration: %v", err)
 	}
 
-	router := api.SetupRouter(cfg)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(cfg); err != nil {
+			log.Fatalf("migration failed: %v", err)
+		}
+		log.Println("migrations complete")
+		return
+	}
+
+	router, cleanup, err := api.SetupRouter(cfg)
+	if err != nil {
+		log.Fatalf("failed to set up router: %v", err)
+	}
 
 	srv := &http.Server{
 		Addr:         cfg.ServerAddress,
@@ -50,5 +63,17 @@ func main() {
 		log.Fatalf("server forced to shutdown: %v", err)
 	}
 
+	if err := cleanup(ctx); err != nil {
+		log.Printf("cleanup error: %v", err)
+	}
+
 	log.Println("server stopped")
 }
+
+// migrate applies pending schema migrations for the configured backend.
+func migrate(cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	return repository.Migrate(ctx, cfg)
+}
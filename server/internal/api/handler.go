@@ -2,11 +2,14 @@
 package api
 
 import (
-	"context"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/abhisheksharm-3/shrtn/internal/model"
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
 	"github.com/abhisheksharm-3/shrtn/internal/service"
 	"github.com/gin-gonic/gin"
 )
@@ -16,14 +19,18 @@ type URLHandler struct {
 	urlService       *service.URLService
 	analyticsService *service.AnalyticsService
 	metadataService  *service.MetadataService
+	passwordAttempts *service.PasswordLimiter
+	disabledLink     DisabledLinkResponse
 }
 
 // NewURLHandler creates a new URLHandler.
-func NewURLHandler(urlService *service.URLService, analyticsService *service.AnalyticsService, metadataService *service.MetadataService) *URLHandler {
+func NewURLHandler(urlService *service.URLService, analyticsService *service.AnalyticsService, metadataService *service.MetadataService, passwordAttempts *service.PasswordLimiter, disabledLink DisabledLinkResponse) *URLHandler {
 	return &URLHandler{
 		urlService:       urlService,
 		analyticsService: analyticsService,
 		metadataService:  metadataService,
+		passwordAttempts: passwordAttempts,
+		disabledLink:     disabledLink,
 	}
 }
 
@@ -39,23 +46,59 @@ func (h *URLHandler) ShortenURL(c *gin.Context) {
 	}
 
 	url, err := h.urlService.Create(c.Request.Context(), input)
+	if writeUTMConflict(c, err) || writeDomainBlocked(c, err) {
+		return
+	}
 	if err != nil {
 		status := http.StatusInternalServerError
 		code := "creation_failed"
 
-		switch err {
-		case service.ErrInvalidURL:
+		switch {
+		case errors.Is(err, service.ErrInvalidURL):
 			status = http.StatusBadRequest
 			code = "invalid_url"
-		case service.ErrURLBlocked:
+		case errors.Is(err, service.ErrURLBlocked):
 			status = http.StatusBadRequest
 			code = "url_blocked"
-		case service.ErrShortCodeExists:
+		case errors.Is(err, service.ErrUnresolvableHost):
+			status = http.StatusBadRequest
+			code = "unresolvable_host"
+		case errors.Is(err, service.ErrShortCodeExists):
 			status = http.StatusConflict
 			code = "code_exists"
-		case service.ErrShortCodeTooShort, service.ErrShortCodeInvalid:
+		case errors.Is(err, service.ErrShortCodeUnavailable):
+			status = http.StatusServiceUnavailable
+			code = "code_unavailable"
+		case errors.Is(err, service.ErrShortCodeTooShort), errors.Is(err, service.ErrShortCodeInvalid):
 			status = http.StatusBadRequest
 			code = "invalid_code"
+		case errors.Is(err, service.ErrInvalidExpiry), errors.Is(err, service.ErrExpiryConflict):
+			status = http.StatusBadRequest
+			code = "invalid_expiry"
+		case errors.Is(err, service.ErrInvalidActiveFrom), errors.Is(err, service.ErrActiveFromConflict):
+			status = http.StatusBadRequest
+			code = "invalid_active_from"
+		case errors.Is(err, service.ErrInvalidTimezone):
+			status = http.StatusBadRequest
+			code = "invalid_timezone"
+		case errors.Is(err, service.ErrInvalidMaxClicks):
+			status = http.StatusBadRequest
+			code = "invalid_max_clicks"
+		case errors.Is(err, service.ErrInvalidPassword):
+			status = http.StatusBadRequest
+			code = "invalid_password"
+		case errors.Is(err, service.ErrInvalidRedirect):
+			status = http.StatusBadRequest
+			code = "invalid_redirect_status"
+		case errors.Is(err, service.ErrInvalidUTM):
+			status = http.StatusBadRequest
+			code = "invalid_utm"
+		case errors.Is(err, service.ErrInvalidVariants):
+			status = http.StatusBadRequest
+			code = "invalid_variants"
+		case errors.Is(err, service.ErrTooManyVariants):
+			status = http.StatusBadRequest
+			code = "too_many_variants"
 		}
 
 		c.JSON(status, gin.H{
@@ -68,6 +111,40 @@ func (h *URLHandler) ShortenURL(c *gin.Context) {
 	c.JSON(http.StatusCreated, url)
 }
 
+// writeUTMConflict writes a 400 response listing the conflicting parameters
+// when err is a *service.UTMConflictError, and reports whether it did.
+func writeUTMConflict(c *gin.Context, err error) bool {
+	var conflict *service.UTMConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":     conflict.Error(),
+		"code":      "utm_conflict",
+		"conflicts": conflict.Conflicts,
+	})
+	return true
+}
+
+// writeDomainBlocked writes a 400 response naming the domain rule that
+// refused the destination when err is a *service.DomainBlockedError, and
+// reports whether it did.
+func writeDomainBlocked(c *gin.Context, err error) bool {
+	var blocked *service.DomainBlockedError
+	if !errors.As(err, &blocked) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": blocked.Error(),
+		"code":  "url_blocked",
+		"host":  blocked.Host,
+		"rule":  blocked.Rule,
+	})
+	return true
+}
+
 // GetURLByShortCode handles GET /api/:shortCode requests.
 func (h *URLHandler) GetURLByShortCode(c *gin.Context) {
 	shortCode := c.Param("shortCode")
@@ -102,27 +179,108 @@ func (h *URLHandler) RedirectURL(c *gin.Context) {
 		return
 	}
 
-	url, err := h.urlService.GetByShortCode(c.Request.Context(), shortCode)
-	if err != nil {
+	resolution, err := h.urlService.Resolve(c.Request.Context(), service.ResolveRequest{
+		ShortCode: shortCode,
+		Path:      c.Param("path"),
+		Query:     c.Request.URL.Query(),
+		UserAgent: c.Request.UserAgent(),
+		ClientIP:  h.analyticsService.ClientIP(c.Request),
+		Variant:   variantCookie(c),
+	})
+	if errors.Is(err, service.ErrPasswordRequired) {
+		renderPage(c, http.StatusForbidden, passwordPage)
+		return
+	}
+	if !h.handleResolveError(c, err) {
+		return
+	}
+
+	h.redirect(c, resolution, resolution.URL.RedirectStatus)
+}
+
+// UnlockURL handles POST /:shortCode requests submitting the password of a
+// protected link. Failed attempts are limited per link and client IP, and
+// per link across all clients.
+func (h *URLHandler) UnlockURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+	// c.ClientIP takes the closest address not set by a trusted proxy, so
+	// clients cannot pick their own limiter key with X-Forwarded-For.
+	attemptIP := c.ClientIP()
+	if !h.passwordAttempts.Allow(shortCode, attemptIP) {
+		renderPage(c, http.StatusTooManyRequests, tooManyAttemptsPage)
+		return
+	}
+
+	resolution, err := h.urlService.Resolve(c.Request.Context(), service.ResolveRequest{
+		ShortCode: shortCode,
+		Password:  c.PostForm("password"),
+		Path:      c.Param("path"),
+		Query:     c.Request.URL.Query(),
+		UserAgent: c.Request.UserAgent(),
+		ClientIP:  h.analyticsService.ClientIP(c.Request),
+		Variant:   variantCookie(c),
+	})
+	if errors.Is(err, service.ErrPasswordRequired) || errors.Is(err, service.ErrPasswordIncorrect) {
+		retry := passwordPage
+		retry.Error = "Incorrect password."
+		renderPage(c, http.StatusForbidden, retry)
+		return
+	}
+
+	// Any other outcome means the password was right or not needed.
+	h.passwordAttempts.Succeed(shortCode, attemptIP)
+	if !h.handleResolveError(c, err) {
+		return
+	}
+
+	h.redirect(c, resolution, http.StatusSeeOther)
+}
+
+// handleResolveError writes the response for a failed Resolve and reports
+// whether the caller should go on to redirect.
+func (h *URLHandler) handleResolveError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, service.ErrURLExpired):
+		renderPage(c, http.StatusGone, expiredPage)
+	case errors.Is(err, service.ErrURLExhausted):
+		renderPage(c, http.StatusGone, exhaustedPage)
+	case errors.Is(err, service.ErrURLNotActive):
+		renderPage(c, http.StatusNotFound, notActivePage)
+	case errors.Is(err, service.ErrURLDisabled):
+		renderDisabled(c, h.disabledLink)
+	case errors.Is(err, service.ErrURLBlocked):
+		renderPage(c, http.StatusForbidden, blockedPage)
+	default:
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "URL not found",
 			"code":  "not_found",
 		})
-		return
 	}
+	return false
+}
 
-	bgCtx := context.Background()
-	go func() {
-		_ = h.analyticsService.RecordClick(bgCtx, url.ID, c.Request)
-	}()
-	go func() {
-		_ = h.urlService.IncrementClicks(bgCtx, url.ID, url.Clicks)
-	}()
+// redirect records the click and sends the client to the destination,
+// remembering the variant served so the visitor keeps seeing it.
+func (h *URLHandler) redirect(c *gin.Context, resolution *service.Resolution, status int) {
+	url := resolution.URL
+	_ = h.analyticsService.RecordClick(c.Request.Context(), url, resolution.Variant, c.Request)
+	if url.MaxClicks == 0 {
+		_ = h.urlService.IncrementClicks(url.ID)
+	}
 
-	c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
-	c.Header("Pragma", "no-cache")
-	c.Header("Expires", "0")
-	c.Redirect(http.StatusMovedPermanently, url.OriginalURL)
+	if maxAge := h.urlService.RedirectCacheMaxAge(url); maxAge > 0 && status == url.RedirectStatus {
+		c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+	} else {
+		c.Header("Cache-Control", "no-cache, no-store, must-revalidate")
+		c.Header("Pragma", "no-cache")
+		c.Header("Expires", "0")
+	}
+	if resolution.Variant != "" {
+		setVariantCookie(c, url.ShortCode, resolution.Variant)
+	}
+	c.Redirect(status, resolution.Destination)
 }
 
 // GetAllURLs handles GET /api/urls requests.
@@ -142,6 +300,142 @@ func (h *URLHandler) GetAllURLs(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// UpdateURL handles PATCH /api/:shortCode requests.
+func (h *URLHandler) UpdateURL(c *gin.Context) {
+	shortCode := c.Param("shortCode")
+
+	var input model.URLUpdate
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid input format",
+			"code":  "invalid_input",
+		})
+		return
+	}
+
+	url, err := h.urlService.Update(c.Request.Context(), shortCode, input, requestActor(c))
+	if errors.Is(err, repository.ErrURLNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "URL not found",
+			"code":  "not_found",
+		})
+		return
+	}
+	if writeUTMConflict(c, err) || writeDomainBlocked(c, err) {
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		code := "update_failed"
+
+		switch {
+		case errors.Is(err, service.ErrVersionConflict):
+			status = http.StatusConflict
+			code = "version_conflict"
+		case errors.Is(err, service.ErrNoChanges):
+			status = http.StatusBadRequest
+			code = "invalid_input"
+		case errors.Is(err, service.ErrInvalidURL):
+			status = http.StatusBadRequest
+			code = "invalid_url"
+		case errors.Is(err, service.ErrURLBlocked):
+			status = http.StatusBadRequest
+			code = "url_blocked"
+		case errors.Is(err, service.ErrUnresolvableHost):
+			status = http.StatusBadRequest
+			code = "unresolvable_host"
+		case errors.Is(err, service.ErrInvalidExpiry), errors.Is(err, service.ErrExpiryConflict):
+			status = http.StatusBadRequest
+			code = "invalid_expiry"
+		case errors.Is(err, service.ErrInvalidActiveFrom), errors.Is(err, service.ErrActiveFromConflict):
+			status = http.StatusBadRequest
+			code = "invalid_active_from"
+		case errors.Is(err, service.ErrInvalidTimezone):
+			status = http.StatusBadRequest
+			code = "invalid_timezone"
+		case errors.Is(err, service.ErrInvalidMaxClicks):
+			status = http.StatusBadRequest
+			code = "invalid_max_clicks"
+		case errors.Is(err, service.ErrInvalidPassword):
+			status = http.StatusBadRequest
+			code = "invalid_password"
+		case errors.Is(err, service.ErrInvalidRedirect):
+			status = http.StatusBadRequest
+			code = "invalid_redirect_status"
+		case errors.Is(err, service.ErrInvalidVariants):
+			status = http.StatusBadRequest
+			code = "invalid_variants"
+		case errors.Is(err, service.ErrTooManyVariants):
+			status = http.StatusBadRequest
+			code = "too_many_variants"
+		}
+
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+			"code":  code,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, url)
+}
+
+// DisableURL handles POST /api/:shortCode/disable requests.
+func (h *URLHandler) DisableURL(c *gin.Context) {
+	url, err := h.urlService.Disable(c.Request.Context(), c.Param("shortCode"), requestActor(c))
+	h.writeLifecycleResult(c, url, err)
+}
+
+// EnableURL handles POST /api/:shortCode/enable requests.
+func (h *URLHandler) EnableURL(c *gin.Context) {
+	url, err := h.urlService.Enable(c.Request.Context(), c.Param("shortCode"), requestActor(c))
+	h.writeLifecycleResult(c, url, err)
+}
+
+// writeLifecycleResult writes the response for a disable or enable request.
+func (h *URLHandler) writeLifecycleResult(c *gin.Context, url *model.URL, err error) {
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, url)
+	case errors.Is(err, repository.ErrURLNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "URL not found",
+			"code":  "not_found",
+		})
+	case errors.Is(err, service.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{
+			"error": err.Error(),
+			"code":  "version_conflict",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update URL",
+			"code":  "update_failed",
+		})
+	}
+}
+
+// GetURLHistory handles GET /api/:shortCode/history requests.
+func (h *URLHandler) GetURLHistory(c *gin.Context) {
+	changes, err := h.urlService.History(c.Request.Context(), c.Param("shortCode"))
+	if errors.Is(err, repository.ErrURLNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "URL not found",
+			"code":  "not_found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to retrieve URL history",
+			"code":  "retrieval_failed",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": changes})
+}
+
 // DeleteURL handles DELETE /api/:shortCode requests.
 func (h *URLHandler) DeleteURL(c *gin.Context) {
 	shortCode := c.Param("shortCode")
@@ -173,6 +467,50 @@ func (h *URLHandler) DeleteURL(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// ListTrash handles GET /api/trash requests.
+func (h *URLHandler) ListTrash(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	response, err := h.urlService.ListTrash(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to retrieve trash",
+			"code":  "retrieval_failed",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RestoreURL handles POST /api/trash/:shortCode/restore requests.
+func (h *URLHandler) RestoreURL(c *gin.Context) {
+	url, err := h.urlService.Restore(c.Request.Context(), c.Param("shortCode"))
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, url)
+	case errors.Is(err, repository.ErrURLNotFound), errors.Is(err, service.ErrShortCodeEmpty):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "URL not found in trash",
+			"code":  "not_found",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to restore URL",
+			"code":  "restore_failed",
+		})
+	}
+}
+
+// GetMetrics handles GET /api/metrics requests.
+func (h *URLHandler) GetMetrics(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"clicks":    h.urlService.ClickStats(),
+		"analytics": h.analyticsService.Stats(),
+	})
+}
+
 // GetLinkPreview handles GET /api/preview requests.
 func (h *URLHandler) GetLinkPreview(c *gin.Context) {
 	targetURL := c.Query("url")
@@ -185,6 +523,13 @@ func (h *URLHandler) GetLinkPreview(c *gin.Context) {
 	}
 
 	preview, err := h.metadataService.FetchPreview(c.Request.Context(), targetURL)
+	if errors.Is(err, service.ErrBlockedTarget) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "url targets an address that is not allowed",
+			"code":  "blocked_target",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{
 			"error": "failed to fetch preview",
@@ -195,3 +540,12 @@ func (h *URLHandler) GetLinkPreview(c *gin.Context) {
 
 	c.JSON(http.StatusOK, preview)
 }
+
+// requestActor identifies who made an API change: the X-Actor header when
+// set, otherwise the client IP.
+func requestActor(c *gin.Context) string {
+	if actor := strings.TrimSpace(c.GetHeader("X-Actor")); actor != "" {
+		return actor
+	}
+	return c.ClientIP()
+}
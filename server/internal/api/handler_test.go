@@ -0,0 +1,234 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestRouter sets up a router on the memory backend with env applied on
+// top of the defaults.
+func newTestRouter(t *testing.T, env map[string]string) http.Handler {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	t.Setenv("STORAGE_BACKEND", "memory")
+	t.Setenv("API_KEY", "")
+	t.Setenv("RATE_LIMIT_BURST", "10000")
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	router, shutdown, err := SetupRouter(cfg)
+	if err != nil {
+		t.Fatalf("failed to set up router: %v", err)
+	}
+	t.Cleanup(func() { _ = shutdown(context.Background()) })
+	return router
+}
+
+// do sends a request from remoteAddr with the given headers and returns the
+// recorded response.
+func do(router http.Handler, req *http.Request, remoteAddr string, header map[string]string) *httptest.ResponseRecorder {
+	req.RemoteAddr = remoteAddr
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// createProtectedLink creates a link with the password "secret".
+func createProtectedLink(t *testing.T, router http.Handler, code string) {
+	t.Helper()
+
+	body := fmt.Sprintf(`{"originalUrl":"https://example.com","customCode":%q,"password":"secret"}`, code)
+	req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if w := do(router, req, "192.0.2.1:1234", nil); w.Code != http.StatusCreated {
+		t.Fatalf("creating link returned %d: %s", w.Code, w.Body)
+	}
+}
+
+// unlock submits password for code and returns the response status.
+func unlock(router http.Handler, code, password, remoteAddr string, header map[string]string) int {
+	form := url.Values{"password": {password}}.Encode()
+	req := httptest.NewRequest(http.MethodPost, "/"+code, strings.NewReader(form))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return do(router, req, remoteAddr, header).Code
+}
+
+func TestUnlockURLLimitsFailedAttempts(t *testing.T) {
+	router := newTestRouter(t, map[string]string{"PASSWORD_MAX_ATTEMPTS": "3"})
+	createProtectedLink(t, router, "locked")
+
+	for i := 0; i < 3; i++ {
+		if status := unlock(router, "locked", "wrong", "198.51.100.7:1000", nil); status != http.StatusForbidden {
+			t.Fatalf("attempt %d returned %d, want 403", i+1, status)
+		}
+	}
+	if status := unlock(router, "locked", "secret", "198.51.100.7:1000", nil); status != http.StatusTooManyRequests {
+		t.Fatalf("attempt past the limit returned %d, want 429", status)
+	}
+	if status := unlock(router, "locked", "secret", "198.51.100.8:1000", nil); status != http.StatusSeeOther {
+		t.Fatalf("another client returned %d, want 303", status)
+	}
+}
+
+func TestUnlockURLResetsOnSuccess(t *testing.T) {
+	router := newTestRouter(t, map[string]string{"PASSWORD_MAX_ATTEMPTS": "3"})
+	createProtectedLink(t, router, "reset")
+
+	for i := 0; i < 2; i++ {
+		unlock(router, "reset", "wrong", "198.51.100.7:1000", nil)
+	}
+	if status := unlock(router, "reset", "secret", "198.51.100.7:1000", nil); status != http.StatusSeeOther {
+		t.Fatalf("correct password returned %d, want 303", status)
+	}
+	for i := 0; i < 3; i++ {
+		if status := unlock(router, "reset", "wrong", "198.51.100.7:1000", nil); status != http.StatusForbidden {
+			t.Fatalf("attempt %d after success returned %d, want 403", i+1, status)
+		}
+	}
+}
+
+func TestUnlockURLLimitExpiresWithWindow(t *testing.T) {
+	const window = 2 * time.Second
+	router := newTestRouter(t, map[string]string{
+		"PASSWORD_MAX_ATTEMPTS":   "2",
+		"PASSWORD_ATTEMPT_WINDOW": window.String(),
+	})
+	createProtectedLink(t, router, "window")
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		unlock(router, "window", "wrong", "198.51.100.7:1000", nil)
+	}
+	status := unlock(router, "window", "wrong", "198.51.100.7:1000", nil)
+	if time.Since(start) >= window {
+		t.Skip("password checks took longer than the attempt window")
+	}
+	if status != http.StatusTooManyRequests {
+		t.Fatalf("attempt past the limit returned %d, want 429", status)
+	}
+
+	time.Sleep(time.Until(start.Add(window + 100*time.Millisecond)))
+	if status := unlock(router, "window", "wrong", "198.51.100.7:1000", nil); status != http.StatusForbidden {
+		t.Fatalf("attempt after the window returned %d, want 403", status)
+	}
+}
+
+func TestUnlockURLIgnoresSpoofedForwardedFor(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        map[string]string
+		remoteAddr string
+		header     func(i int) map[string]string
+	}{
+		{
+			name:       "direct client",
+			remoteAddr: "198.51.100.7:1000",
+			header: func(i int) map[string]string {
+				return map[string]string{"X-Forwarded-For": fmt.Sprintf("10.0.0.%d", i)}
+			},
+		},
+		{
+			name:       "behind a trusted proxy that appends the client address",
+			env:        map[string]string{"TRUSTED_PROXY": "127.0.0.1"},
+			remoteAddr: "127.0.0.1:1000",
+			header: func(i int) map[string]string {
+				return map[string]string{"X-Forwarded-For": fmt.Sprintf("10.0.0.%d, 198.51.100.7", i)}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := map[string]string{"PASSWORD_MAX_ATTEMPTS": "3", "TRUSTED_PROXY": ""}
+			for key, value := range tt.env {
+				env[key] = value
+			}
+			router := newTestRouter(t, env)
+			createProtectedLink(t, router, "spoof")
+
+			for i := 0; i < 3; i++ {
+				unlock(router, "spoof", "wrong", tt.remoteAddr, tt.header(i))
+			}
+			if status := unlock(router, "spoof", "wrong", tt.remoteAddr, tt.header(99)); status != http.StatusTooManyRequests {
+				t.Fatalf("attempt with a new X-Forwarded-For returned %d, want 429", status)
+			}
+		})
+	}
+}
+
+func TestUnlockURLLimitsAttemptsPerLink(t *testing.T) {
+	router := newTestRouter(t, map[string]string{
+		"PASSWORD_MAX_ATTEMPTS":      "3",
+		"PASSWORD_LINK_MAX_ATTEMPTS": "4",
+	})
+	createProtectedLink(t, router, "perlink")
+
+	for i := 0; i < 4; i++ {
+		addr := fmt.Sprintf("198.51.100.%d:1000", i+1)
+		if status := unlock(router, "perlink", "wrong", addr, nil); status != http.StatusForbidden {
+			t.Fatalf("attempt %d returned %d, want 403", i+1, status)
+		}
+	}
+	if status := unlock(router, "perlink", "secret", "198.51.100.99:1000", nil); status != http.StatusTooManyRequests {
+		t.Fatalf("attempt past the link limit returned %d, want 429", status)
+	}
+}
+
+func TestRedirectURLAsksForPasswordBeforeLinkState(t *testing.T) {
+	router := newTestRouter(t, nil)
+	createProtectedLink(t, router, "paused")
+
+	disable := httptest.NewRequest(http.MethodPost, "/api/paused/disable", nil)
+	if w := do(router, disable, "192.0.2.1:1234", nil); w.Code != http.StatusOK {
+		t.Fatalf("disabling link returned %d: %s", w.Code, w.Body)
+	}
+
+	visit := httptest.NewRequest(http.MethodGet, "/paused", nil)
+	if w := do(router, visit, "198.51.100.7:1000", nil); w.Code != http.StatusForbidden {
+		t.Fatalf("visit without password returned %d, want 403", w.Code)
+	}
+	if status := unlock(router, "paused", "wrong", "198.51.100.7:1000", nil); status != http.StatusForbidden {
+		t.Fatalf("wrong password returned %d, want 403", status)
+	}
+	if status := unlock(router, "paused", "secret", "198.51.100.7:1000", nil); status != http.StatusNotFound {
+		t.Fatalf("correct password returned %d, want the disabled link's 404", status)
+	}
+}
+
+func TestShortenURLRejectsTakenCustomCode(t *testing.T) {
+	router := newTestRouter(t, nil)
+
+	shorten := func() *httptest.ResponseRecorder {
+		body := `{"originalUrl":"https://example.com","customCode":"taken"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return do(router, req, "192.0.2.1:1234", nil)
+	}
+
+	if w := shorten(); w.Code != http.StatusCreated {
+		t.Fatalf("first create returned %d: %s", w.Code, w.Body)
+	}
+	w := shorten()
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `"code_exists"`) {
+		t.Fatalf("second create returned %d: %s, want 409 code_exists", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,156 @@
+// Package api provides HTML pages served on the redirect path.
+package api
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageCSP allows the inline styles of the pages below and nothing else.
+const pageCSP = "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'"
+
+var pageTemplate = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}} · shrtn</title>
+<style>
+  body { margin: 0; min-height: 100vh; display: flex; align-items: center; justify-content: center;
+         font-family: system-ui, -apple-system, "Segoe UI", sans-serif; background: #0a0a0a; color: #fafafa; }
+  main { max-width: 28rem; padding: 2rem; text-align: center; }
+  .brand { font-weight: 700; letter-spacing: -0.02em; color: #a1a1aa; margin-bottom: 2rem; }
+  h1 { font-size: 1.5rem; margin: 0 0 0.75rem; }
+  p { color: #a1a1aa; line-height: 1.5; margin: 0; }
+  p.error { color: #f87171; margin-top: 1rem; }
+  form { display: flex; gap: 0.5rem; margin-top: 1.5rem; }
+  input { flex: 1; padding: 0.6rem 0.75rem; border-radius: 0.5rem; border: 1px solid #3f3f46;
+          background: #18181b; color: inherit; font: inherit; }
+  button { padding: 0.6rem 1rem; border-radius: 0.5rem; border: 0; background: #fafafa; color: #0a0a0a;
+           font: inherit; font-weight: 600; cursor: pointer; }
+</style>
+</head>
+<body>
+<main>
+  <div class="brand">shrtn</div>
+  <h1>{{.Heading}}</h1>
+  <p>{{.Message}}</p>
+  {{- if .Error}}
+  <p class="error">{{.Error}}</p>
+  {{- end}}
+  {{- if .PasswordForm}}
+  <form method="post">
+    <input type="password" name="password" aria-label="Password" autocomplete="current-password" required autofocus>
+    <button type="submit">Continue</button>
+  </form>
+  {{- end}}
+</main>
+</body>
+</html>
+`))
+
+// page holds the content of a branded HTML page. Code is the error code
+// returned instead of the page to clients that ask for JSON.
+type page struct {
+	Code    string
+	Title   string
+	Heading string
+	Message string
+	Error   string
+
+	// PasswordForm adds a password field that posts back to the same URL.
+	PasswordForm bool
+}
+
+var (
+	expiredPage = page{
+		Code:    "link_expired",
+		Title:   "Link expired",
+		Heading: "This link has expired",
+		Message: "The short link you followed is no longer available.",
+	}
+	exhaustedPage = page{
+		Code:    "link_exhausted",
+		Title:   "Link used up",
+		Heading: "This link has reached its click limit",
+		Message: "The short link you followed could only be opened a limited number of times.",
+	}
+	disabledPage = page{
+		Code:    "link_disabled",
+		Title:   "Link unavailable",
+		Heading: "This link is unavailable",
+		Message: "The short link you followed has been paused by its owner.",
+	}
+	notActivePage = page{
+		Code:    "link_not_active",
+		Title:   "Not yet available",
+		Heading: "This link is not available yet",
+		Message: "The short link you followed has not gone live. Please check back later.",
+	}
+	blockedPage = page{
+		Code:    "url_blocked",
+		Title:   "Link blocked",
+		Heading: "This link has been blocked",
+		Message: "The short link you followed leads to a site that is not allowed.",
+	}
+	passwordPage = page{
+		Code:         "password_required",
+		Title:        "Password required",
+		Heading:      "This link is password protected",
+		Message:      "Enter the password to continue to the destination.",
+		PasswordForm: true,
+	}
+	tooManyAttemptsPage = page{
+		Code:    "too_many_attempts",
+		Title:   "Too many attempts",
+		Heading: "Too many incorrect passwords",
+		Message: "Please wait a few minutes before trying again.",
+	}
+)
+
+// DisabledLinkResponse configures the response to visits of disabled links.
+type DisabledLinkResponse struct {
+	// Status is 404 or 410.
+	Status int
+	// Page is a custom HTML page served instead of the built-in one.
+	Page []byte
+}
+
+// renderDisabled writes the response for a visit to a disabled link.
+func renderDisabled(c *gin.Context, response DisabledLinkResponse) {
+	if response.Page == nil || c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		renderPage(c, response.Status, disabledPage)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(response.Status, "text/html; charset=utf-8", response.Page)
+}
+
+// renderPage writes p as an HTML response with the given status, or as a
+// JSON error when the client prefers JSON.
+func renderPage(c *gin.Context, status int, p page) {
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+		c.JSON(status, gin.H{
+			"error": p.Heading,
+			"code":  p.Code,
+		})
+		return
+	}
+
+	c.Header("Content-Security-Policy", pageCSP)
+	c.Header("Cache-Control", "no-store")
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+
+	if err := pageTemplate.Execute(c.Writer, p); err != nil {
+		log.Printf("failed to render %q page: %v", p.Title, err)
+		if !c.Writer.Written() {
+			c.String(http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		}
+	}
+}
@@ -2,6 +2,10 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/abhisheksharm-3/shrtn/internal/config"
@@ -13,8 +17,9 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// SetupRouter configures and returns the application router.
-func SetupRouter(cfg *config.Config) *gin.Engine {
+// SetupRouter configures and returns the application router together with a
+// shutdown function that releases the resources it opened.
+func SetupRouter(cfg *config.Config) (*gin.Engine, func(context.Context) error, error) {
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -22,10 +27,20 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	r := gin.New()
 	r.Use(gin.Recovery())
 
+	// Forwarding headers are only trusted from the configured proxy, the
+	// same rule AnalyticsService.ClientIP applies.
+	var trustedProxies []string
+	if cfg.TrustedProxy != "" {
+		trustedProxies = []string{cfg.TrustedProxy, "127.0.0.1"}
+	}
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, nil, fmt.Errorf("invalid TRUSTED_PROXY: %w", err)
+	}
+
 	corsConfig := cors.Config{
 		AllowOrigins:     cfg.CORSOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Actor"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -39,14 +54,87 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		CleanupInterval:   5 * time.Minute,
 	}))
 
-	urlRepo := repository.NewAppwriteURLRepository(cfg)
-	analyticsRepo := repository.NewAppwriteAnalyticsRepository(cfg)
+	disabledLink := DisabledLinkResponse{Status: cfg.DisabledLinkStatus}
+	if cfg.DisabledLinkPage != "" {
+		page, err := os.ReadFile(cfg.DisabledLinkPage)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read disabled link page: %w", err)
+		}
+		disabledLink.Page = page
+	}
+
+	store, err := repository.NewStore(context.Background(), cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize %s storage: %w", cfg.StorageBackend, err)
+	}
 
-	urlService := service.NewURLService(urlRepo)
-	analyticsService := service.NewAnalyticsService(analyticsRepo, "")
-	metadataService := service.NewMetadataService()
+	var geo *service.GeoIP
+	if cfg.GeoIPDBPath != "" {
+		geo, err = service.NewGeoIP(cfg.GeoIPDBPath, cfg.GeoIPReloadInterval)
+		if err != nil {
+			store.Close()
+			return nil, nil, err
+		}
+	}
 
-	urlHandler := NewURLHandler(urlService, analyticsService, metadataService)
+	var domains *service.DomainRules
+	if cfg.DomainRulesPath != "" {
+		domains, err = service.NewDomainRules(cfg.DomainRulesPath, cfg.DomainRulesReloadInterval)
+		if err != nil {
+			if geo != nil {
+				geo.Stop()
+			}
+			store.Close()
+			return nil, nil, err
+		}
+	}
+
+	var spool *service.AnalyticsSpool
+	if cfg.AnalyticsSpoolPath != "" {
+		spool, err = service.NewAnalyticsSpool(store.Analytics, service.SpoolConfig{
+			Path:            cfg.AnalyticsSpoolPath,
+			MaxBytes:        int64(cfg.AnalyticsSpoolMaxBytes),
+			ReplayInterval:  cfg.AnalyticsSpoolReplayInterval,
+			ReplayBatchSize: cfg.AnalyticsBatchSize,
+		})
+		if err != nil {
+			if domains != nil {
+				domains.Stop()
+			}
+			if geo != nil {
+				geo.Stop()
+			}
+			store.Close()
+			return nil, nil, err
+		}
+	}
+
+	clickCounter := service.NewClickCounter(store.URLs, cfg.ClickFlushInterval)
+	urlService := service.NewURLService(store.URLs, clickCounter, geo, service.URLConfig{
+		DefaultRedirectStatus: cfg.DefaultRedirectStatus,
+		RedirectCacheMaxAge:   cfg.RedirectCacheMaxAge,
+		DNSCheck:              cfg.DestinationDNSCheck,
+		DNSTimeout:            cfg.DestinationDNSTimeout,
+		Domains:               domains,
+	})
+	expirySweeper := service.NewExpirySweeper(store.URLs, store.Analytics, cfg.ExpiredLinkAction, cfg.ExpirySweepInterval)
+	trashPurger := service.NewTrashPurger(store.URLs, store.Analytics, cfg.TrashRetention, cfg.TrashPurgeInterval)
+	analyticsService := service.NewAnalyticsService(store.Analytics, spool, service.AnalyticsConfig{
+		TrustedProxy:  cfg.TrustedProxy,
+		QueueSize:     cfg.AnalyticsQueueSize,
+		Workers:       cfg.AnalyticsWorkers,
+		BatchSize:     cfg.AnalyticsBatchSize,
+		FlushInterval: cfg.AnalyticsFlushInterval,
+		QueuePolicy:   cfg.AnalyticsQueuePolicy,
+	})
+	metadataService := service.NewMetadataService(service.NewOutboundClient(service.OutboundPolicy{
+		AllowedSchemes: cfg.OutboundAllowedSchemes,
+		AllowedPorts:   cfg.OutboundAllowedPorts,
+	}))
+
+	passwordAttempts := service.NewPasswordLimiter(cfg.PasswordMaxAttempts, cfg.PasswordLinkMaxAttempts, cfg.PasswordAttemptWindow)
+
+	urlHandler := NewURLHandler(urlService, analyticsService, metadataService, passwordAttempts, disabledLink)
 
 	api := r.Group("/api")
 	api.Use(middleware.APIKeyAuth(cfg.APIKey))
@@ -54,11 +142,26 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		api.POST("/shorten", urlHandler.ShortenURL)
 		api.GET("/urls", urlHandler.GetAllURLs)
 		api.GET("/preview", urlHandler.GetLinkPreview)
+		api.GET("/metrics", urlHandler.GetMetrics)
+		api.GET("/trash", urlHandler.ListTrash)
+		api.POST("/trash/:shortCode/restore", urlHandler.RestoreURL)
 		api.GET("/:shortCode", urlHandler.GetURLByShortCode)
+		api.PATCH("/:shortCode", urlHandler.UpdateURL)
+		api.GET("/:shortCode/history", urlHandler.GetURLHistory)
+		api.POST("/:shortCode/disable", urlHandler.DisableURL)
+		api.POST("/:shortCode/enable", urlHandler.EnableURL)
+		api.GET("/:shortCode/analytics/variants", urlHandler.GetVariantStats)
+		api.GET("/:shortCode/rules", urlHandler.ListRules)
+		api.POST("/:shortCode/rules", urlHandler.CreateRule)
+		api.PUT("/:shortCode/rules/:ruleId", urlHandler.UpdateRule)
+		api.DELETE("/:shortCode/rules/:ruleId", urlHandler.DeleteRule)
 		api.DELETE("/:shortCode", urlHandler.DeleteURL)
 	}
 
 	r.GET("/:shortCode", urlHandler.RedirectURL)
+	r.POST("/:shortCode", urlHandler.UnlockURL)
+	r.GET("/:shortCode/*path", urlHandler.RedirectURL)
+	r.POST("/:shortCode/*path", urlHandler.UnlockURL)
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -67,5 +170,22 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		})
 	})
 
-	return r
+	shutdown := func(ctx context.Context) error {
+		expirySweeper.Stop()
+		trashPurger.Stop()
+		if geo != nil {
+			geo.Stop()
+		}
+		if domains != nil {
+			domains.Stop()
+		}
+		errs := []error{analyticsService.Close(ctx)}
+		if spool != nil {
+			errs = append(errs, spool.Close())
+		}
+		errs = append(errs, clickCounter.Stop(ctx), store.Close())
+		return errors.Join(errs...)
+	}
+
+	return r, shutdown, nil
 }
@@ -0,0 +1,130 @@
+// Package api provides HTTP handlers for link targeting rules.
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+	"github.com/abhisheksharm-3/shrtn/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ListRules handles GET /api/:shortCode/rules requests.
+func (h *URLHandler) ListRules(c *gin.Context) {
+	rules, err := h.urlService.Rules(c.Request.Context(), c.Param("shortCode"))
+	if err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreateRule handles POST /api/:shortCode/rules requests.
+func (h *URLHandler) CreateRule(c *gin.Context) {
+	var input model.RedirectRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid input format",
+			"code":  "invalid_input",
+		})
+		return
+	}
+
+	rule, err := h.urlService.AddRule(c.Request.Context(), c.Param("shortCode"), input, requestActor(c))
+	if err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateRule handles PUT /api/:shortCode/rules/:ruleId requests.
+func (h *URLHandler) UpdateRule(c *gin.Context) {
+	var input model.RedirectRuleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid input format",
+			"code":  "invalid_input",
+		})
+		return
+	}
+
+	rule, err := h.urlService.UpdateRule(c.Request.Context(), c.Param("shortCode"), c.Param("ruleId"), input, requestActor(c))
+	if err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteRule handles DELETE /api/:shortCode/rules/:ruleId requests.
+func (h *URLHandler) DeleteRule(c *gin.Context) {
+	err := h.urlService.DeleteRule(c.Request.Context(), c.Param("shortCode"), c.Param("ruleId"), requestActor(c))
+	if err != nil {
+		writeRuleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// writeRuleError writes the response for a failed rule operation.
+func writeRuleError(c *gin.Context, err error) {
+	if writeDomainBlocked(c, err) {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	code := "rule_update_failed"
+	message := err.Error()
+
+	switch {
+	case errors.Is(err, repository.ErrURLNotFound):
+		status = http.StatusNotFound
+		code = "not_found"
+		message = "URL not found"
+	case errors.Is(err, service.ErrRuleNotFound):
+		status = http.StatusNotFound
+		code = "rule_not_found"
+	case errors.Is(err, service.ErrVersionConflict):
+		status = http.StatusConflict
+		code = "version_conflict"
+	case errors.Is(err, service.ErrInvalidURL):
+		status = http.StatusBadRequest
+		code = "invalid_url"
+	case errors.Is(err, service.ErrURLBlocked):
+		status = http.StatusBadRequest
+		code = "url_blocked"
+	case errors.Is(err, service.ErrUnresolvableHost):
+		status = http.StatusBadRequest
+		code = "unresolvable_host"
+	case errors.Is(err, service.ErrInvalidPlatform):
+		status = http.StatusBadRequest
+		code = "invalid_platform"
+	case errors.Is(err, service.ErrInvalidPosition):
+		status = http.StatusBadRequest
+		code = "invalid_position"
+	case errors.Is(err, service.ErrInvalidCountry):
+		status = http.StatusBadRequest
+		code = "invalid_country"
+	case errors.Is(err, service.ErrGeoIPUnavailable):
+		status = http.StatusBadRequest
+		code = "geoip_unavailable"
+	case errors.Is(err, service.ErrInvalidSchedule):
+		status = http.StatusBadRequest
+		code = "invalid_schedule"
+	case errors.Is(err, service.ErrTooManyRules):
+		status = http.StatusBadRequest
+		code = "too_many_rules"
+	}
+
+	c.JSON(status, gin.H{
+		"error": message,
+		"code":  code,
+	})
+}
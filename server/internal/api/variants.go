@@ -0,0 +1,66 @@
+// Package api provides HTTP handlers for A/B split destinations.
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// variantCookieName holds the A/B variant a visitor was assigned. It is
+	// scoped to the short link's path, so each link has its own.
+	variantCookieName = "shrtn_variant"
+	variantCookieAge  = 30 * 24 * 60 * 60
+)
+
+// variantCookie returns the variant assigned to the visitor on an earlier
+// visit, or "" when there is none.
+func variantCookie(c *gin.Context) string {
+	value, err := c.Cookie(variantCookieName)
+	if err != nil {
+		return ""
+	}
+	return value
+}
+
+// setVariantCookie remembers variant as the visitor's assignment for the
+// link with shortCode.
+func setVariantCookie(c *gin.Context, shortCode, variant string) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(variantCookieName, variant, variantCookieAge, "/"+shortCode, "", secure, true)
+}
+
+// GetVariantStats handles GET /api/:shortCode/analytics/variants requests.
+func (h *URLHandler) GetVariantStats(c *gin.Context) {
+	url, err := h.urlService.GetByShortCode(c.Request.Context(), c.Param("shortCode"))
+	if errors.Is(err, repository.ErrURLNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "URL not found",
+			"code":  "not_found",
+		})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to retrieve URL",
+			"code":  "retrieval_failed",
+		})
+		return
+	}
+
+	stats, err := h.analyticsService.VariantStats(c.Request.Context(), url)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to retrieve variant analytics",
+			"code":  "retrieval_failed",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"variants": stats})
+}
@@ -2,27 +2,88 @@
 package config
 
 import (
-	"errors"
+	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// Supported storage backends.
+const (
+	StorageAppwrite = "appwrite"
+	StorageMemory   = "memory"
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+)
+
+// StorageBackends lists every accepted STORAGE_BACKEND value.
+var StorageBackends = []string{StorageAppwrite, StoragePostgres, StorageSQLite, StorageMemory}
+
 // Config holds all application configuration.
 type Config struct {
 	Environment        string
 	ServerAddress      string
+	StorageBackend     string
 	AppwriteEndpoint   string
 	AppwriteProjectID  string
 	AppwriteAPIKey     string
 	AppwriteCollection string
 	AppwriteDatabase   string
+	DatabaseURL        string
+	DatabaseMaxConns   int
+	SQLitePath         string
+	AutoMigrate        bool
 	CORSOrigins        []string
 	APIKey             string
 	RateLimitPerMinute int
 	RateLimitBurst     int
+	ClickFlushInterval time.Duration
+	CacheSize          int
+	CacheTTL           time.Duration
+	CacheNegativeTTL   time.Duration
+
+	ExpiredLinkAction   string
+	ExpirySweepInterval time.Duration
+
+	TrashRetention     time.Duration
+	TrashPurgeInterval time.Duration
+
+	PasswordMaxAttempts     int
+	PasswordLinkMaxAttempts int
+	PasswordAttemptWindow   time.Duration
+
+	DefaultRedirectStatus int
+	RedirectCacheMaxAge   time.Duration
+
+	DisabledLinkStatus int
+	DisabledLinkPage   string
+
+	OutboundAllowedSchemes []string
+	OutboundAllowedPorts   []int
+
+	DestinationDNSCheck   string
+	DestinationDNSTimeout time.Duration
+
+	DomainRulesPath           string
+	DomainRulesReloadInterval time.Duration
+
+	TrustedProxy        string
+	GeoIPDBPath         string
+	GeoIPReloadInterval time.Duration
+
+	AnalyticsQueueSize     int
+	AnalyticsWorkers       int
+	AnalyticsBatchSize     int
+	AnalyticsFlushInterval time.Duration
+	AnalyticsQueuePolicy   string
+
+	AnalyticsSpoolPath           string
+	AnalyticsSpoolMaxBytes       int
+	AnalyticsSpoolReplayInterval time.Duration
 }
 
 // Load reads configuration from environment variables.
@@ -32,17 +93,70 @@ func Load() (*Config, error) {
 	cfg := &Config{
 		Environment:        getEnv("ENVIRONMENT", "development"),
 		ServerAddress:      getEnv("SERVER_ADDRESS", ":8080"),
+		StorageBackend:     strings.ToLower(getEnv("STORAGE_BACKEND", StorageAppwrite)),
 		AppwriteEndpoint:   getEnv("APPWRITE_ENDPOINT", ""),
 		AppwriteProjectID:  getEnv("APPWRITE_PROJECT_ID", ""),
 		AppwriteAPIKey:     getEnv("APPWRITE_API_KEY", ""),
 		AppwriteCollection: getEnv("APPWRITE_COLLECTION_ID", ""),
 		AppwriteDatabase:   getEnv("APPWRITE_DATABASE_ID", ""),
-		CORSOrigins:        parseCORSOrigins(getEnv("CORS_ORIGINS", "http://localhost:5173")),
+		DatabaseURL:        getEnv("DATABASE_URL", ""),
+		DatabaseMaxConns:   getEnvInt("DATABASE_MAX_CONNS", 10),
+		SQLitePath:         getEnv("SQLITE_PATH", "shrtn.db"),
+		AutoMigrate:        getEnvBool("DB_AUTO_MIGRATE", true),
+		CORSOrigins:        parseList(getEnv("CORS_ORIGINS", "http://localhost:5173")),
 		APIKey:             getEnv("API_KEY", ""),
 		RateLimitPerMinute: getEnvInt("RATE_LIMIT_PER_MINUTE", 60),
 		RateLimitBurst:     getEnvInt("RATE_LIMIT_BURST", 10),
+		ClickFlushInterval: getEnvDuration("CLICK_FLUSH_INTERVAL", 5*time.Second),
+		CacheSize:          getEnvInt("CACHE_SIZE", 10000),
+		CacheTTL:           getEnvDuration("CACHE_TTL", time.Minute),
+		CacheNegativeTTL:   getEnvDuration("CACHE_NEGATIVE_TTL", 10*time.Second),
+
+		ExpiredLinkAction:   strings.ToLower(getEnv("EXPIRED_LINK_ACTION", "archive")),
+		ExpirySweepInterval: getEnvDuration("EXPIRY_SWEEP_INTERVAL", time.Minute),
+
+		TrashRetention:     getEnvDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval: getEnvDuration("TRASH_PURGE_INTERVAL", time.Hour),
+
+		PasswordMaxAttempts:     getEnvInt("PASSWORD_MAX_ATTEMPTS", 5),
+		PasswordLinkMaxAttempts: getEnvInt("PASSWORD_LINK_MAX_ATTEMPTS", 100),
+		PasswordAttemptWindow:   getEnvDuration("PASSWORD_ATTEMPT_WINDOW", 15*time.Minute),
+
+		DefaultRedirectStatus: getEnvInt("DEFAULT_REDIRECT_STATUS", 302),
+		RedirectCacheMaxAge:   getEnvDuration("REDIRECT_CACHE_MAX_AGE", 24*time.Hour),
+
+		DisabledLinkStatus: getEnvInt("DISABLED_LINK_STATUS", 404),
+		DisabledLinkPage:   getEnv("DISABLED_LINK_PAGE", ""),
+
+		OutboundAllowedSchemes: parseList(strings.ToLower(getEnv("OUTBOUND_ALLOWED_SCHEMES", "http,https"))),
+
+		DestinationDNSCheck:   strings.ToLower(getEnv("DESTINATION_DNS_CHECK", "off")),
+		DestinationDNSTimeout: getEnvDuration("DESTINATION_DNS_TIMEOUT", 2*time.Second),
+
+		DomainRulesPath:           getEnv("DOMAIN_RULES_PATH", ""),
+		DomainRulesReloadInterval: getEnvDuration("DOMAIN_RULES_RELOAD_INTERVAL", 10*time.Second),
+
+		TrustedProxy:        getEnv("TRUSTED_PROXY", ""),
+		GeoIPDBPath:         getEnv("GEOIP_DB_PATH", ""),
+		GeoIPReloadInterval: getEnvDuration("GEOIP_RELOAD_INTERVAL", time.Minute),
+
+		AnalyticsQueueSize:     getEnvInt("ANALYTICS_QUEUE_SIZE", 10000),
+		AnalyticsWorkers:       getEnvInt("ANALYTICS_WORKERS", 2),
+		AnalyticsBatchSize:     getEnvInt("ANALYTICS_BATCH_SIZE", 100),
+		AnalyticsFlushInterval: getEnvDuration("ANALYTICS_FLUSH_INTERVAL", 2*time.Second),
+		AnalyticsQueuePolicy:   strings.ToLower(getEnv("ANALYTICS_QUEUE_POLICY", "drop")),
+
+		AnalyticsSpoolPath:           getEnv("ANALYTICS_SPOOL_PATH", ""),
+		AnalyticsSpoolMaxBytes:       getEnvInt("ANALYTICS_SPOOL_MAX_BYTES", 64<<20),
+		AnalyticsSpoolReplayInterval: getEnvDuration("ANALYTICS_SPOOL_REPLAY_INTERVAL", 30*time.Second),
 	}
 
+	ports, err := parsePorts(getEnv("OUTBOUND_ALLOWED_PORTS", "80,443"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.OutboundAllowedPorts = ports
+
 	if err := cfg.validate(); err != nil {
 		return nil, err
 	}
@@ -51,18 +165,65 @@ func Load() (*Config, error) {
 }
 
 func (c *Config) validate() error {
-	if c.AppwriteProjectID == "" {
-		return errors.New("APPWRITE_PROJECT_ID is required")
+	if c.AnalyticsQueuePolicy != "drop" && c.AnalyticsQueuePolicy != "block" {
+		return fmt.Errorf("ANALYTICS_QUEUE_POLICY must be drop or block, got %q", c.AnalyticsQueuePolicy)
 	}
-	if c.AppwriteAPIKey == "" {
-		return errors.New("APPWRITE_API_KEY is required")
+
+	if c.ExpiredLinkAction != "archive" && c.ExpiredLinkAction != "purge" {
+		return fmt.Errorf("EXPIRED_LINK_ACTION must be archive or purge, got %q", c.ExpiredLinkAction)
 	}
-	if c.AppwriteCollection == "" {
-		return errors.New("APPWRITE_COLLECTION_ID is required")
+
+	switch c.DefaultRedirectStatus {
+	case 301, 302, 307, 308:
+	default:
+		return fmt.Errorf("DEFAULT_REDIRECT_STATUS must be 301, 302, 307 or 308, got %d", c.DefaultRedirectStatus)
 	}
-	if c.AppwriteDatabase == "" {
-		return errors.New("APPWRITE_DATABASE_ID is required")
+
+	switch c.DestinationDNSCheck {
+	case "off", "block", "strict":
+	default:
+		return fmt.Errorf("DESTINATION_DNS_CHECK must be off, block or strict, got %q", c.DestinationDNSCheck)
+	}
+
+	if c.TrashRetention < 0 {
+		return fmt.Errorf("TRASH_RETENTION must not be negative, got %s", c.TrashRetention)
+	}
+
+	if c.DisabledLinkStatus != 404 && c.DisabledLinkStatus != 410 {
+		return fmt.Errorf("DISABLED_LINK_STATUS must be 404 or 410, got %d", c.DisabledLinkStatus)
+	}
+
+	var required map[string]string
+	switch c.StorageBackend {
+	case StorageAppwrite:
+		required = map[string]string{
+			"APPWRITE_PROJECT_ID":    c.AppwriteProjectID,
+			"APPWRITE_API_KEY":       c.AppwriteAPIKey,
+			"APPWRITE_COLLECTION_ID": c.AppwriteCollection,
+			"APPWRITE_DATABASE_ID":   c.AppwriteDatabase,
+		}
+	case StoragePostgres:
+		required = map[string]string{"DATABASE_URL": c.DatabaseURL}
+	case StorageSQLite:
+		required = map[string]string{"SQLITE_PATH": c.SQLitePath}
+	case StorageMemory:
+	default:
+		return fmt.Errorf("unsupported STORAGE_BACKEND %q (expected one of: %s)",
+			c.StorageBackend, strings.Join(StorageBackends, ", "))
 	}
+
+	var missing []string
+	for key, value := range required {
+		if value == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("STORAGE_BACKEND=%s requires missing settings: %s",
+			c.StorageBackend, strings.Join(missing, ", "))
+	}
+
 	return nil
 }
 
@@ -87,11 +248,30 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
-func parseCORSOrigins(origins string) []string {
-	if origins == "" {
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if durVal, err := time.ParseDuration(value); err == nil {
+			return durVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
+// parseList splits a comma-separated list, dropping empty items.
+func parseList(value string) []string {
+	if value == "" {
 		return []string{}
 	}
-	parts := strings.Split(origins, ",")
+	parts := strings.Split(value, ",")
 	result := make([]string, 0, len(parts))
 	for _, part := range parts {
 		trimmed := strings.TrimSpace(part)
@@ -101,3 +281,17 @@ func parseCORSOrigins(origins string) []string {
 	}
 	return result
 }
+
+// parsePorts parses a comma-separated list of TCP ports.
+func parsePorts(value string) ([]int, error) {
+	items := parseList(value)
+	ports := make([]int, 0, len(items))
+	for _, item := range items {
+		port, err := strconv.Atoi(item)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("OUTBOUND_ALLOWED_PORTS must list ports from 1 to 65535, got %q", item)
+		}
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
@@ -11,6 +11,8 @@ type AnalyticsEntry struct {
 	UserAgent string    `json:"userAgent"`
 	IPAddress string    `json:"ipAddress"`
 	Referer   string    `json:"referer"`
+	Campaign  string    `json:"campaign,omitempty"`
+	Variant   string    `json:"variant,omitempty"`
 }
 
 // URLStats represents aggregated statistics for a URL.
@@ -0,0 +1,17 @@
+// Package model defines domain models for the URL shortener.
+package model
+
+import "time"
+
+// URLChange records one edit of a URL. Version is the version the edit
+// produced; PreviousURL is the destination it replaced.
+type URLChange struct {
+	ID          string    `json:"id"`
+	URLId       string    `json:"urlId"`
+	Version     int       `json:"version"`
+	PreviousURL string    `json:"previousUrl"`
+	OriginalURL string    `json:"originalUrl"`
+	Fields      []string  `json:"fields"`
+	Actor       string    `json:"actor"`
+	ChangedAt   time.Time `json:"changedAt"`
+}
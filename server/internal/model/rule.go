@@ -0,0 +1,59 @@
+// Package model defines domain models for the URL shortener.
+package model
+
+import "time"
+
+// Platforms a targeting rule can match, as parsed from the User-Agent.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+	PlatformOther   = "other"
+)
+
+// Days a time-window rule can match.
+const (
+	DayMonday    = "mon"
+	DayTuesday   = "tue"
+	DayWednesday = "wed"
+	DayThursday  = "thu"
+	DayFriday    = "fri"
+	DaySaturday  = "sat"
+	DaySunday    = "sun"
+)
+
+// RedirectRule sends visits matching its conditions to Destination instead
+// of the link's OriginalURL. Rules are evaluated in order and the first
+// match wins. An empty condition matches every visit. Countries are
+// ISO 3166-1 alpha-2 codes.
+//
+// Days, From and Until form a time window in the link's Timezone. From and
+// Until are "15:04" times; a window with Until before From runs past
+// midnight and belongs to the day it starts on.
+type RedirectRule struct {
+	ID          string    `json:"id"`
+	Platforms   []string  `json:"platforms,omitempty"`
+	Countries   []string  `json:"countries,omitempty"`
+	Days        []string  `json:"days,omitempty"`
+	From        string    `json:"from,omitempty"`
+	Until       string    `json:"until,omitempty"`
+	Destination string    `json:"destination"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// RedirectRuleInput represents the input to create or replace a rule.
+// Position is the zero-based place of the rule in the evaluation order;
+// nil appends new rules and keeps replaced rules where they are.
+type RedirectRuleInput struct {
+	Platforms   []string `json:"platforms"`
+	Countries   []string `json:"countries"`
+	Days        []string `json:"days"`
+	From        string   `json:"from"`
+	Until       string   `json:"until"`
+	Destination string   `json:"destination" binding:"required"`
+	Position    *int     `json:"position,omitempty"`
+	Version     *int     `json:"version,omitempty"`
+}
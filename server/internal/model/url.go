@@ -12,12 +12,125 @@ type URL struct {
 	UpdatedAt   time.Time `json:"updatedAt"`
 	Clicks      int       `json:"clicks"`
 	UserID      string    `json:"userId,omitempty"`
+	Version     int       `json:"version"`
+
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	ArchivedAt *time.Time `json:"archivedAt,omitempty"`
+
+	// DeletedAt is set while the link is in the trash. Trashed links do
+	// not redirect but keep their short code until they are purged.
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+
+	// ActiveFrom is when the link goes live; it does not redirect before.
+	ActiveFrom *time.Time `json:"activeFrom,omitempty"`
+	// Timezone is the IANA time zone the time windows of Rules are in;
+	// empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+
+	// Disabled links keep their short code and analytics but do not
+	// redirect until enabled again.
+	Disabled bool `json:"disabled,omitempty"`
+
+	// MaxClicks limits how many redirects the link serves; 0 means
+	// unlimited. RemainingClicks is derived from it when reporting a URL.
+	MaxClicks       int  `json:"maxClicks,omitempty"`
+	RemainingClicks *int `json:"remainingClicks,omitempty"`
+
+	// PasswordHash is a bcrypt hash; it is never serialised.
+	PasswordHash string `json:"-"`
+
+	// RedirectStatus is 301, 302, 307 or 308; 0 means the server default.
+	RedirectStatus int `json:"redirectStatus,omitempty"`
+
+	// ForwardQuery merges the query string of the short link request into
+	// the destination. ForwardPath makes the link a prefix link: the path
+	// after the short code is appended to the destination path.
+	ForwardQuery bool `json:"forwardQuery,omitempty"`
+	ForwardPath  bool `json:"forwardPath,omitempty"`
+
+	// UTM holds the UTM parameters merged into OriginalURL at creation.
+	UTM *UTM `json:"utm,omitempty"`
+
+	// Rules are the targeting rules evaluated before OriginalURL.
+	Rules []RedirectRule `json:"rules,omitempty"`
+
+	// Variants split visits not matched by a rule across weighted
+	// destinations; OriginalURL is only used when there are none.
+	Variants []Variant `json:"variants,omitempty"`
+}
+
+// UTM is a set of UTM campaign parameters.
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// IsZero reports whether no UTM parameter is set.
+func (u UTM) IsZero() bool {
+	return u == UTM{}
+}
+
+// IsActive reports whether the URL has gone live as of now.
+func (u *URL) IsActive(now time.Time) bool {
+	return u.ActiveFrom == nil || !now.Before(*u.ActiveFrom)
+}
+
+// IsExpired reports whether the URL has expired or been archived as of now.
+func (u *URL) IsExpired(now time.Time) bool {
+	if u.ArchivedAt != nil {
+		return true
+	}
+	return u.ExpiresAt != nil && !now.Before(*u.ExpiresAt)
 }
 
 // URLInput represents the input to create a shortened URL.
 type URLInput struct {
 	OriginalURL string `json:"originalUrl" binding:"required"`
 	CustomCode  string `json:"customCode,omitempty"`
+
+	// ExpiresAt and TTL are mutually exclusive. TTL is a Go duration
+	// string such as "72h" measured from creation.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	TTL       string     `json:"ttl,omitempty"`
+
+	ActiveFrom *time.Time `json:"activeFrom,omitempty"`
+	Timezone   string     `json:"timezone,omitempty"`
+
+	MaxClicks int    `json:"maxClicks,omitempty"`
+	Password  string `json:"password,omitempty"`
+
+	RedirectStatus int  `json:"redirectStatus,omitempty"`
+	ForwardQuery   bool `json:"forwardQuery,omitempty"`
+	ForwardPath    bool `json:"forwardPath,omitempty"`
+
+	// UTM parameters are added to the query of OriginalURL.
+	UTM *UTM `json:"utm,omitempty"`
+
+	Variants []Variant `json:"variants,omitempty"`
+}
+
+// URLUpdate represents a partial update of a shortened URL. Nil fields are
+// left unchanged; an empty Password removes the password, RemoveExpiry
+// clears the expiry and RemoveActiveFrom makes the link live at once. When
+// Version is set it must match the stored version.
+type URLUpdate struct {
+	OriginalURL      *string    `json:"originalUrl,omitempty"`
+	ExpiresAt        *time.Time `json:"expiresAt,omitempty"`
+	TTL              *string    `json:"ttl,omitempty"`
+	RemoveExpiry     bool       `json:"removeExpiry,omitempty"`
+	ActiveFrom       *time.Time `json:"activeFrom,omitempty"`
+	RemoveActiveFrom bool       `json:"removeActiveFrom,omitempty"`
+	Timezone         *string    `json:"timezone,omitempty"`
+	MaxClicks        *int       `json:"maxClicks,omitempty"`
+	Password         *string    `json:"password,omitempty"`
+	RedirectStatus   *int       `json:"redirectStatus,omitempty"`
+	ForwardQuery     *bool      `json:"forwardQuery,omitempty"`
+	ForwardPath      *bool      `json:"forwardPath,omitempty"`
+	Variants         *[]Variant `json:"variants,omitempty"`
+	Version          *int       `json:"version,omitempty"`
 }
 
 // URLListResponse represents a paginated list of URLs.
@@ -0,0 +1,17 @@
+// Package model defines domain models for the URL shortener.
+package model
+
+// Variant is one of the weighted destinations of an A/B split link. Each
+// visitor is assigned a variant with probability Weight over the sum of all
+// weights and keeps it on later visits.
+type Variant struct {
+	ID          string `json:"id"`
+	Destination string `json:"destination"`
+	Weight      int    `json:"weight"`
+}
+
+// VariantStats reports the recorded clicks of a variant.
+type VariantStats struct {
+	Variant
+	Clicks int `json:"clicks"`
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
@@ -19,23 +20,40 @@ import (
 	"github.com/appwrite/sdk-for-go/query"
 )
 
-var (
-	ErrURLNotFound = errors.New("url not found")
-	ErrDecoding    = errors.New("error decoding response")
-)
+var ErrDecoding = errors.New("error decoding response")
 
 const (
 	collectionAnalytics = "analytics"
+	collectionHistory   = "url_history"
 	defaultTimeout      = 10 * time.Second
 )
 
 type urlDocument struct {
-	ID          string  `json:"$id"`
-	ShortCode   string  `json:"ShortCode"`
-	OriginalURL string  `json:"OriginalURL"`
-	CreatedAt   string  `json:"CreatedAt"`
-	UpdatedAt   string  `json:"UpdatedAt"`
-	Clicks      float64 `json:"Clicks"`
+	ID             string  `json:"$id"`
+	ShortCode      string  `json:"ShortCode"`
+	OriginalURL    string  `json:"OriginalURL"`
+	CreatedAt      string  `json:"CreatedAt"`
+	UpdatedAt      string  `json:"UpdatedAt"`
+	Clicks         float64 `json:"Clicks"`
+	ExpiresAt      *string `json:"ExpiresAt"`
+	ArchivedAt     *string `json:"ArchivedAt"`
+	DeletedAt      *string `json:"DeletedAt"`
+	ActiveFrom     *string `json:"ActiveFrom"`
+	Timezone       string  `json:"Timezone"`
+	Disabled       bool    `json:"Disabled"`
+	MaxClicks      float64 `json:"MaxClicks"`
+	PasswordHash   string  `json:"PasswordHash"`
+	Version        float64 `json:"Version"`
+	RedirectStatus float64 `json:"RedirectStatus"`
+	ForwardQuery   bool    `json:"ForwardQuery"`
+	ForwardPath    bool    `json:"ForwardPath"`
+	UTMSource      string  `json:"UTMSource"`
+	UTMMedium      string  `json:"UTMMedium"`
+	UTMCampaign    string  `json:"UTMCampaign"`
+	UTMTerm        string  `json:"UTMTerm"`
+	UTMContent     string  `json:"UTMContent"`
+	Rules          string  `json:"Rules"`
+	Variants       string  `json:"Variants"`
 }
 
 type urlDocumentList struct {
@@ -70,6 +88,10 @@ func GetAppwriteClient(cfg *config.Config) *AppwriteClient {
 type AppwriteURLRepository struct {
 	config    *config.Config
 	databases *databases.Databases
+
+	// writeMu serialises the read-modify-write updates of IncrementClicks,
+	// ConsumeClick, Update, Trash and Restore within this process.
+	writeMu sync.Mutex
 }
 
 // NewAppwriteURLRepository creates a new Appwrite URL repository.
@@ -81,26 +103,61 @@ func NewAppwriteURLRepository(cfg *config.Config) *AppwriteURLRepository {
 	}
 }
 
-// Create inserts a new URL document and returns its ID.
+// Create inserts a new URL document and returns its ID. Short code
+// uniqueness relies on a unique index on the ShortCode attribute, which makes
+// Appwrite reject duplicates with 409 Conflict.
 func (r *AppwriteURLRepository) Create(ctx context.Context, url model.URL) (string, error) {
 	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
+	rules, err := encodeRules(url.Rules)
+	if err != nil {
+		return "", err
+	}
+	variants, err := encodeVariants(url.Variants)
+	if err != nil {
+		return "", err
+	}
+
 	uniqueID := id.Unique()
+	utm := utmValue(url.UTM)
 	document, err := r.databases.CreateDocument(
 		r.config.AppwriteDatabase,
 		r.config.AppwriteCollection,
 		uniqueID,
 		map[string]interface{}{
-			"ID":          uniqueID,
-			"ShortCode":   url.ShortCode,
-			"OriginalURL": url.OriginalURL,
-			"CreatedAt":   url.CreatedAt.Format(time.RFC3339),
-			"UpdatedAt":   url.UpdatedAt.Format(time.RFC3339),
-			"Clicks":      url.Clicks,
+			"ID":             uniqueID,
+			"ShortCode":      url.ShortCode,
+			"OriginalURL":    url.OriginalURL,
+			"CreatedAt":      url.CreatedAt.Format(time.RFC3339),
+			"UpdatedAt":      url.UpdatedAt.Format(time.RFC3339),
+			"Clicks":         url.Clicks,
+			"ExpiresAt":      appwriteTime(url.ExpiresAt),
+			"ArchivedAt":     appwriteTime(url.ArchivedAt),
+			"DeletedAt":      appwriteTime(url.DeletedAt),
+			"ActiveFrom":     appwriteTime(url.ActiveFrom),
+			"Timezone":       url.Timezone,
+			"Disabled":       url.Disabled,
+			"MaxClicks":      url.MaxClicks,
+			"PasswordHash":   url.PasswordHash,
+			"Version":        url.Version,
+			"RedirectStatus": url.RedirectStatus,
+			"ForwardQuery":   url.ForwardQuery,
+			"ForwardPath":    url.ForwardPath,
+			"UTMSource":      utm.Source,
+			"UTMMedium":      utm.Medium,
+			"UTMCampaign":    utm.Campaign,
+			"UTMTerm":        utm.Term,
+			"UTMContent":     utm.Content,
+			"Rules":          rules,
+			"Variants":       variants,
 		},
 	)
 	if err != nil {
+		var awErr *client.AppwriteError
+		if errors.As(err, &awErr) && awErr.GetStatusCode() == http.StatusConflict {
+			return "", ErrDuplicateShortCode
+		}
 		return "", fmt.Errorf("failed to create URL document: %w", err)
 	}
 
@@ -136,7 +193,7 @@ func (r *AppwriteURLRepository) GetByShortCode(ctx context.Context, shortCode st
 		return nil, ErrURLNotFound
 	}
 
-	return documentToURL(urlList.Documents[0]), nil
+	return documentToURL(urlList.Documents[0])
 }
 
 // GetAll retrieves paginated URLs and total count.
@@ -145,6 +202,7 @@ func (r *AppwriteURLRepository) GetAll(ctx context.Context, limit, offset int) (
 	defer cancel()
 
 	queries := []string{
+		query.IsNull("DeletedAt"),
 		query.Limit(limit),
 		query.Offset(offset),
 		query.OrderDesc("CreatedAt"),
@@ -166,14 +224,20 @@ func (r *AppwriteURLRepository) GetAll(ctx context.Context, limit, offset int) (
 
 	urls := make([]model.URL, 0, len(urlList.Documents))
 	for _, doc := range urlList.Documents {
-		urls = append(urls, *documentToURL(doc))
+		url, err := documentToURL(doc)
+		if err != nil {
+			return nil, 0, err
+		}
+		urls = append(urls, *url)
 	}
 
 	return urls, urlList.Total, nil
 }
 
-// UpdateClicks increments the click count for a URL.
-func (r *AppwriteURLRepository) UpdateClicks(ctx context.Context, docID string, clicks int) error {
+// IncrementClicks adds delta to the click count for a URL. Appwrite has no
+// atomic increment, so the stored count is read and rewritten under writeMu,
+// which keeps it from racing ConsumeClick and Update within this process.
+func (r *AppwriteURLRepository) IncrementClicks(ctx context.Context, docID string, delta int) error {
 	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
 	defer cancel()
 
@@ -181,12 +245,33 @@ func (r *AppwriteURLRepository) UpdateClicks(ctx context.Context, docID string,
 		return fmt.Errorf("document ID cannot be empty")
 	}
 
-	_, err := r.databases.UpdateDocument(
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	document, err := r.databases.GetDocument(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		docID,
+	)
+	if err != nil {
+		var awErr *client.AppwriteError
+		if errors.As(err, &awErr) && awErr.GetStatusCode() == http.StatusNotFound {
+			return ErrURLNotFound
+		}
+		return fmt.Errorf("failed to read URL clicks: %w", err)
+	}
+
+	var doc urlDocument
+	if err := document.Decode(&doc); err != nil {
+		return fmt.Errorf("%w: %v", ErrDecoding, err)
+	}
+
+	_, err = r.databases.UpdateDocument(
 		r.config.AppwriteDatabase,
 		r.config.AppwriteCollection,
 		docID,
 		r.databases.WithUpdateDocumentData(map[string]interface{}{
-			"Clicks":    clicks + 1,
+			"Clicks":    int(doc.Clicks) + delta,
 			"UpdatedAt": time.Now().UTC().Format(time.RFC3339),
 		}),
 	)
@@ -197,6 +282,223 @@ func (r *AppwriteURLRepository) UpdateClicks(ctx context.Context, docID string,
 	return nil
 }
 
+// ConsumeClick adds one click to a click-limited URL while it is below
+// MaxClicks. Appwrite has no conditional update, so the check is only atomic
+// within this process; instances sharing a collection may overshoot the limit
+// by the number of concurrent writers.
+func (r *AppwriteURLRepository) ConsumeClick(ctx context.Context, docID string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return 0, fmt.Errorf("document ID cannot be empty")
+	}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	document, err := r.databases.GetDocument(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		docID,
+	)
+	if err != nil {
+		var awErr *client.AppwriteError
+		if errors.As(err, &awErr) && awErr.GetStatusCode() == http.StatusNotFound {
+			return 0, ErrURLNotFound
+		}
+		return 0, fmt.Errorf("failed to read URL clicks: %w", err)
+	}
+
+	var doc urlDocument
+	if err := document.Decode(&doc); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrDecoding, err)
+	}
+
+	clicks, maxClicks := int(doc.Clicks), int(doc.MaxClicks)
+	if maxClicks <= 0 || clicks >= maxClicks {
+		return 0, ErrClickLimitReached
+	}
+
+	_, err = r.databases.UpdateDocument(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		docID,
+		r.databases.WithUpdateDocumentData(map[string]interface{}{
+			"Clicks":    clicks + 1,
+			"UpdatedAt": time.Now().UTC().Format(time.RFC3339),
+		}),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update URL clicks: %w", err)
+	}
+
+	return maxClicks - clicks - 1, nil
+}
+
+// Update stores the mutable fields of url and records change in the
+// url_history collection. Like ConsumeClick, the version check is only atomic
+// within this process.
+func (r *AppwriteURLRepository) Update(ctx context.Context, url model.URL, change model.URLChange) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if url.ID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	document, err := r.databases.GetDocument(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		url.ID,
+	)
+	if err != nil {
+		var awErr *client.AppwriteError
+		if errors.As(err, &awErr) && awErr.GetStatusCode() == http.StatusNotFound {
+			return ErrURLNotFound
+		}
+		return fmt.Errorf("failed to read URL document: %w", err)
+	}
+
+	var doc urlDocument
+	if err := document.Decode(&doc); err != nil {
+		return fmt.Errorf("%w: %v", ErrDecoding, err)
+	}
+	if int(doc.Version) != url.Version-1 {
+		return ErrVersionConflict
+	}
+
+	rules, err := encodeRules(url.Rules)
+	if err != nil {
+		return err
+	}
+	variants, err := encodeVariants(url.Variants)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.databases.UpdateDocument(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		url.ID,
+		r.databases.WithUpdateDocumentData(map[string]interface{}{
+			"OriginalURL":    url.OriginalURL,
+			"UpdatedAt":      url.UpdatedAt.Format(time.RFC3339),
+			"ExpiresAt":      appwriteTime(url.ExpiresAt),
+			"ArchivedAt":     appwriteTime(url.ArchivedAt),
+			"ActiveFrom":     appwriteTime(url.ActiveFrom),
+			"Timezone":       url.Timezone,
+			"Disabled":       url.Disabled,
+			"MaxClicks":      url.MaxClicks,
+			"PasswordHash":   url.PasswordHash,
+			"Version":        url.Version,
+			"RedirectStatus": url.RedirectStatus,
+			"ForwardQuery":   url.ForwardQuery,
+			"ForwardPath":    url.ForwardPath,
+			"Rules":          rules,
+			"Variants":       variants,
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update URL document: %w", err)
+	}
+
+	_, err = r.databases.CreateDocument(
+		r.config.AppwriteDatabase,
+		collectionHistory,
+		id.Unique(),
+		map[string]interface{}{
+			"urlId":         url.ID,
+			"version":       change.Version,
+			"previousUrl":   change.PreviousURL,
+			"originalUrl":   change.OriginalURL,
+			"changedFields": joinFields(change.Fields),
+			"actor":         change.Actor,
+			"changedAt":     change.ChangedAt.Format(time.RFC3339),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record URL history: %w", err)
+	}
+
+	return nil
+}
+
+// historyPageSize bounds how many history documents ListHistory fetches per
+// request.
+const historyPageSize = 100
+
+// ListHistory returns the recorded changes of a URL, newest first, paging
+// through the history collection until it is exhausted.
+func (r *AppwriteURLRepository) ListHistory(ctx context.Context, docID string) ([]model.URLChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var changes []model.URLChange
+	cursor := ""
+	for {
+		queries := []string{
+			query.Equal("urlId", docID),
+			query.OrderDesc("version"),
+			query.Limit(historyPageSize),
+		}
+		if cursor != "" {
+			queries = append(queries, query.CursorAfter(cursor))
+		}
+
+		response, err := r.databases.ListDocuments(
+			r.config.AppwriteDatabase,
+			collectionHistory,
+			r.databases.WithListDocumentsQueries(queries),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query URL history: %w", err)
+		}
+
+		var result struct {
+			Documents []struct {
+				ID            string  `json:"$id"`
+				URLId         string  `json:"urlId"`
+				Version       float64 `json:"version"`
+				PreviousURL   string  `json:"previousUrl"`
+				OriginalURL   string  `json:"originalUrl"`
+				ChangedFields string  `json:"changedFields"`
+				Actor         string  `json:"actor"`
+				ChangedAt     string  `json:"changedAt"`
+			} `json:"documents"`
+		}
+		if err := response.Decode(&result); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrDecoding, err)
+		}
+
+		for _, doc := range result.Documents {
+			changedAt, _ := time.Parse(time.RFC3339, doc.ChangedAt)
+			changes = append(changes, model.URLChange{
+				ID:          doc.ID,
+				URLId:       doc.URLId,
+				Version:     int(doc.Version),
+				PreviousURL: doc.PreviousURL,
+				OriginalURL: doc.OriginalURL,
+				Fields:      splitFields(doc.ChangedFields),
+				Actor:       doc.Actor,
+				ChangedAt:   changedAt.UTC(),
+			})
+		}
+		if len(result.Documents) < historyPageSize {
+			break
+		}
+		cursor = result.Documents[len(result.Documents)-1].ID
+	}
+
+	if changes == nil {
+		changes = []model.URLChange{}
+	}
+	return changes, nil
+}
+
 // Delete removes a URL document by ID.
 func (r *AppwriteURLRepository) Delete(ctx context.Context, docID string) error {
 	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
@@ -218,6 +520,188 @@ func (r *AppwriteURLRepository) Delete(ctx context.Context, docID string) error
 	return nil
 }
 
+// ListExpired returns unarchived URLs that expired at or before before,
+// oldest first.
+func (r *AppwriteURLRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	queries := []string{
+		query.LessThanEqual("ExpiresAt", before.UTC().Format(time.RFC3339)),
+		query.IsNull("ArchivedAt"),
+		query.IsNull("DeletedAt"),
+		query.OrderAsc("ExpiresAt"),
+		query.Limit(limit),
+	}
+
+	response, err := r.databases.ListDocuments(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		r.databases.WithListDocumentsQueries(queries),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired URL documents: %w", err)
+	}
+
+	var urlList urlDocumentList
+	if err := response.Decode(&urlList); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecoding, err)
+	}
+
+	urls := make([]model.URL, 0, len(urlList.Documents))
+	for _, doc := range urlList.Documents {
+		url, err := documentToURL(doc)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, *url)
+	}
+
+	return urls, nil
+}
+
+// Archive marks a URL document as archived at the given time.
+func (r *AppwriteURLRepository) Archive(ctx context.Context, docID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	archivedAt := at.UTC().Format(time.RFC3339)
+	_, err := r.databases.UpdateDocument(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		docID,
+		r.databases.WithUpdateDocumentData(map[string]interface{}{
+			"ArchivedAt": archivedAt,
+			"UpdatedAt":  archivedAt,
+		}),
+	)
+	if err != nil {
+		var awErr *client.AppwriteError
+		if errors.As(err, &awErr) && awErr.GetStatusCode() == http.StatusNotFound {
+			return ErrURLNotFound
+		}
+		return fmt.Errorf("failed to archive URL document: %w", err)
+	}
+
+	return nil
+}
+
+// Trash moves a URL to the trash.
+func (r *AppwriteURLRepository) Trash(ctx context.Context, docID string, at time.Time) error {
+	deletedAt := at.UTC().Format(time.RFC3339)
+	return r.setDeletedAt(ctx, docID, false, deletedAt, at)
+}
+
+// Restore takes a URL out of the trash.
+func (r *AppwriteURLRepository) Restore(ctx context.Context, docID string, at time.Time) error {
+	return r.setDeletedAt(ctx, docID, true, nil, at)
+}
+
+// setDeletedAt stores deletedAt on the URL document provided its trash state
+// is trashed. The check is only atomic within this process.
+func (r *AppwriteURLRepository) setDeletedAt(ctx context.Context, docID string, trashed bool, deletedAt interface{}, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	r.writeMu.Lock()
+	defer r.writeMu.Unlock()
+
+	document, err := r.databases.GetDocument(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		docID,
+	)
+	if err != nil {
+		var awErr *client.AppwriteError
+		if errors.As(err, &awErr) && awErr.GetStatusCode() == http.StatusNotFound {
+			return ErrURLNotFound
+		}
+		return fmt.Errorf("failed to read URL document: %w", err)
+	}
+
+	var doc urlDocument
+	if err := document.Decode(&doc); err != nil {
+		return fmt.Errorf("%w: %v", ErrDecoding, err)
+	}
+	if (doc.DeletedAt != nil) != trashed {
+		return ErrURLNotFound
+	}
+
+	_, err = r.databases.UpdateDocument(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		docID,
+		r.databases.WithUpdateDocumentData(map[string]interface{}{
+			"DeletedAt": deletedAt,
+			"UpdatedAt": at.UTC().Format(time.RFC3339),
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update URL document: %w", err)
+	}
+
+	return nil
+}
+
+// ListTrash returns paginated trashed URLs, most recently deleted first, and
+// their total count.
+func (r *AppwriteURLRepository) ListTrash(ctx context.Context, limit, offset int) ([]model.URL, int, error) {
+	return r.listTrashed(ctx, []string{
+		query.IsNotNull("DeletedAt"),
+		query.OrderDesc("DeletedAt"),
+		query.Limit(limit),
+		query.Offset(offset),
+	})
+}
+
+// ListTrashedBefore returns URLs trashed at or before before, oldest first.
+func (r *AppwriteURLRepository) ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	urls, _, err := r.listTrashed(ctx, []string{
+		query.LessThanEqual("DeletedAt", before.UTC().Format(time.RFC3339)),
+		query.OrderAsc("DeletedAt"),
+		query.Limit(limit),
+	})
+	return urls, err
+}
+
+func (r *AppwriteURLRepository) listTrashed(ctx context.Context, queries []string) ([]model.URL, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	response, err := r.databases.ListDocuments(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		r.databases.WithListDocumentsQueries(queries),
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list trashed URL documents: %w", err)
+	}
+
+	var urlList urlDocumentList
+	if err := response.Decode(&urlList); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrDecoding, err)
+	}
+
+	urls := make([]model.URL, 0, len(urlList.Documents))
+	for _, doc := range urlList.Documents {
+		url, err := documentToURL(doc)
+		if err != nil {
+			return nil, 0, err
+		}
+		urls = append(urls, *url)
+	}
+
+	return urls, urlList.Total, nil
+}
+
 // AppwriteAnalyticsRepository implements AnalyticsRepository using Appwrite.
 type AppwriteAnalyticsRepository struct {
 	config    *config.Config
@@ -252,6 +736,8 @@ func (r *AppwriteAnalyticsRepository) Create(ctx context.Context, entry model.An
 			"userAgent": entry.UserAgent,
 			"ipAddress": entry.IPAddress,
 			"referer":   entry.Referer,
+			"campaign":  entry.Campaign,
+			"variant":   entry.Variant,
 		},
 	)
 	if err != nil {
@@ -261,6 +747,49 @@ func (r *AppwriteAnalyticsRepository) Create(ctx context.Context, entry model.An
 	return document.Id, nil
 }
 
+// CreateBatch inserts entries one document at a time, stopping at the first
+// failure. Entries of URLs that no longer exist are skipped; each URL is
+// looked up once per batch.
+func (r *AppwriteAnalyticsRepository) CreateBatch(ctx context.Context, entries []model.AnalyticsEntry) (int, error) {
+	exists := make(map[string]bool)
+	for i, entry := range entries {
+		if entry.URLId != "" {
+			found, checked := exists[entry.URLId]
+			if !checked {
+				var err error
+				if found, err = r.urlExists(ctx, entry.URLId); err != nil {
+					return i, err
+				}
+				exists[entry.URLId] = found
+			}
+			if !found {
+				continue
+			}
+		}
+		if _, err := r.Create(ctx, entry); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// urlExists reports whether the URL document urlID still exists.
+func (r *AppwriteAnalyticsRepository) urlExists(ctx context.Context, urlID string) (bool, error) {
+	_, err := r.databases.GetDocument(
+		r.config.AppwriteDatabase,
+		r.config.AppwriteCollection,
+		urlID,
+	)
+	if err != nil {
+		var awErr *client.AppwriteError
+		if errors.As(err, &awErr) && awErr.GetStatusCode() == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up URL of analytics entry: %w", err)
+	}
+	return true, nil
+}
+
 // GetByURLID retrieves analytics entries for a URL with pagination.
 func (r *AppwriteAnalyticsRepository) GetByURLID(ctx context.Context, urlID string, limit, offset int) ([]model.AnalyticsEntry, error) {
 	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
@@ -294,6 +823,8 @@ func (r *AppwriteAnalyticsRepository) GetByURLID(ctx context.Context, urlID stri
 			UserAgent string `json:"userAgent"`
 			IPAddress string `json:"ipAddress"`
 			Referer   string `json:"referer"`
+			Campaign  string `json:"campaign"`
+			Variant   string `json:"variant"`
 		} `json:"documents"`
 	}
 	if err := response.Decode(&result); err != nil {
@@ -310,13 +841,86 @@ func (r *AppwriteAnalyticsRepository) GetByURLID(ctx context.Context, urlID stri
 			UserAgent: doc.UserAgent,
 			IPAddress: doc.IPAddress,
 			Referer:   doc.Referer,
+			Campaign:  doc.Campaign,
+			Variant:   doc.Variant,
 		})
 	}
 
 	return entries, nil
 }
 
-func documentToURL(doc urlDocument) *model.URL {
+// CountByVariant counts the analytics entries of a URL served variant,
+// using the total Appwrite reports for the matching documents.
+func (r *AppwriteAnalyticsRepository) CountByVariant(ctx context.Context, urlID, variant string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	queries := []string{
+		query.Equal("urlId", urlID),
+		query.Equal("variant", variant),
+		query.Limit(1),
+	}
+
+	response, err := r.databases.ListDocuments(
+		r.config.AppwriteDatabase,
+		collectionAnalytics,
+		r.databases.WithListDocumentsQueries(queries),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count analytics: %w", err)
+	}
+
+	var result struct {
+		Total int `json:"total"`
+	}
+	if err := response.Decode(&result); err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrDecoding, err)
+	}
+	return result.Total, nil
+}
+
+// analyticsDeleteBatch bounds how many analytics documents DeleteByURLID
+// lists at a time.
+const analyticsDeleteBatch = 100
+
+// DeleteByURLID removes all analytics documents of a URL, one page at a time.
+func (r *AppwriteAnalyticsRepository) DeleteByURLID(ctx context.Context, urlID string) error {
+	for {
+		response, err := r.databases.ListDocuments(
+			r.config.AppwriteDatabase,
+			collectionAnalytics,
+			r.databases.WithListDocumentsQueries([]string{
+				query.Equal("urlId", urlID),
+				query.Limit(analyticsDeleteBatch),
+			}),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to list analytics: %w", err)
+		}
+
+		var result struct {
+			Documents []struct {
+				ID string `json:"$id"`
+			} `json:"documents"`
+		}
+		if err := response.Decode(&result); err != nil {
+			return fmt.Errorf("%w: %v", ErrDecoding, err)
+		}
+
+		for _, doc := range result.Documents {
+			if _, err := r.databases.DeleteDocument(r.config.AppwriteDatabase, collectionAnalytics, doc.ID); err != nil {
+				return fmt.Errorf("failed to delete analytics document: %w", err)
+			}
+		}
+		if len(result.Documents) < analyticsDeleteBatch {
+			return nil
+		}
+	}
+}
+
+// documentToURL converts a URL document to a model.URL, failing with
+// ErrDecoding when its stored rules or variants cannot be decoded.
+func documentToURL(doc urlDocument) (*model.URL, error) {
 	var createdAt, updatedAt time.Time
 	if doc.CreatedAt != "" {
 		createdAt, _ = time.Parse(time.RFC3339, doc.CreatedAt)
@@ -325,12 +929,69 @@ func documentToURL(doc urlDocument) *model.URL {
 		updatedAt, _ = time.Parse(time.RFC3339, doc.UpdatedAt)
 	}
 
+	rules, err := decodeRules(doc.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("%w: document %s: %v", ErrDecoding, doc.ID, err)
+	}
+	variants, err := decodeVariants(doc.Variants)
+	if err != nil {
+		return nil, fmt.Errorf("%w: document %s: %v", ErrDecoding, doc.ID, err)
+	}
+
+	// Documents from before redirect types keep the 301 they were always
+	// served with, as the SQL migration does for existing rows.
+	redirectStatus := int(doc.RedirectStatus)
+	if redirectStatus == 0 {
+		redirectStatus = http.StatusMovedPermanently
+	}
+
 	return &model.URL{
-		ID:          doc.ID,
-		ShortCode:   doc.ShortCode,
-		OriginalURL: doc.OriginalURL,
-		CreatedAt:   createdAt,
-		UpdatedAt:   updatedAt,
-		Clicks:      int(doc.Clicks),
+		ID:             doc.ID,
+		ShortCode:      doc.ShortCode,
+		OriginalURL:    doc.OriginalURL,
+		CreatedAt:      createdAt,
+		UpdatedAt:      updatedAt,
+		Clicks:         int(doc.Clicks),
+		ExpiresAt:      parseAppwriteTime(doc.ExpiresAt),
+		ArchivedAt:     parseAppwriteTime(doc.ArchivedAt),
+		DeletedAt:      parseAppwriteTime(doc.DeletedAt),
+		ActiveFrom:     parseAppwriteTime(doc.ActiveFrom),
+		Timezone:       doc.Timezone,
+		Disabled:       doc.Disabled,
+		MaxClicks:      int(doc.MaxClicks),
+		PasswordHash:   doc.PasswordHash,
+		Version:        int(doc.Version),
+		RedirectStatus: redirectStatus,
+		ForwardQuery:   doc.ForwardQuery,
+		ForwardPath:    doc.ForwardPath,
+		UTM: utmPtr(model.UTM{
+			Source:   doc.UTMSource,
+			Medium:   doc.UTMMedium,
+			Campaign: doc.UTMCampaign,
+			Term:     doc.UTMTerm,
+			Content:  doc.UTMContent,
+		}),
+		Rules:    rules,
+		Variants: variants,
+	}, nil
+}
+
+// appwriteTime formats an optional time for a datetime attribute.
+func appwriteTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func parseAppwriteTime(value *string) *time.Time {
+	if value == nil || *value == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, *value)
+	if err != nil {
+		return nil
 	}
+	t = t.UTC()
+	return &t
 }
@@ -0,0 +1,256 @@
+// Package repository provides a caching decorator for URL lookups.
+package repository
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// CacheConfig configures CachedURLRepository.
+type CacheConfig struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+}
+
+type cacheEntry struct {
+	shortCode string
+	url       *model.URL
+	expiresAt time.Time
+}
+
+// CachedURLRepository decorates a URLRepository with a bounded LRU cache of
+// short code lookups. Unknown codes are cached for NegativeTTL, concurrent
+// misses for the same code share a single backend query, and entries are
+// invalidated when this process creates, updates, archives, trashes,
+// restores or deletes the URL.
+// Other instances observe changes once the TTL expires. Cached click counts
+// may lag behind the stored value by up to TTL.
+type CachedURLRepository struct {
+	next   URLRepository
+	config CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	codes   map[string]string
+	lru     *list.List
+	// gen is bumped on every invalidation so that a lookup which raced
+	// with a write does not cache the value it read before the write.
+	gen uint64
+
+	group singleflight.Group
+}
+
+// NewCachedURLRepository wraps next with a read-through cache.
+func NewCachedURLRepository(next URLRepository, cfg CacheConfig) *CachedURLRepository {
+	return &CachedURLRepository{
+		next:    next,
+		config:  cfg,
+		entries: make(map[string]*list.Element),
+		codes:   make(map[string]string),
+		lru:     list.New(),
+	}
+}
+
+// Create inserts a URL and drops any negative entry for its short code.
+func (r *CachedURLRepository) Create(ctx context.Context, url model.URL) (string, error) {
+	docID, err := r.next.Create(ctx, url)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	r.gen++
+	r.removeLocked(url.ShortCode)
+	r.mu.Unlock()
+
+	return docID, nil
+}
+
+// GetByShortCode serves lookups from the cache, falling back to the wrapped
+// repository on a miss.
+func (r *CachedURLRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	if url, found, ok := r.lookup(shortCode); ok {
+		if !found {
+			return nil, ErrURLNotFound
+		}
+		return url, nil
+	}
+
+	result, err, _ := r.group.Do(shortCode, func() (interface{}, error) {
+		r.mu.Lock()
+		gen := r.gen
+		r.mu.Unlock()
+
+		url, err := r.next.GetByShortCode(context.WithoutCancel(ctx), shortCode)
+		switch {
+		case err == nil:
+			r.store(gen, shortCode, url, r.config.TTL)
+		case errors.Is(err, ErrURLNotFound):
+			r.store(gen, shortCode, nil, r.config.NegativeTTL)
+		}
+		return url, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	url := *result.(*model.URL)
+	return &url, nil
+}
+
+// GetAll delegates to the wrapped repository.
+func (r *CachedURLRepository) GetAll(ctx context.Context, limit, offset int) ([]model.URL, int, error) {
+	return r.next.GetAll(ctx, limit, offset)
+}
+
+// IncrementClicks delegates to the wrapped repository.
+func (r *CachedURLRepository) IncrementClicks(ctx context.Context, docID string, delta int) error {
+	return r.next.IncrementClicks(ctx, docID, delta)
+}
+
+// ConsumeClick delegates to the wrapped repository and evicts the URL so
+// that the reported remaining clicks stay current.
+func (r *CachedURLRepository) ConsumeClick(ctx context.Context, docID string) (int, error) {
+	remaining, err := r.next.ConsumeClick(ctx, docID)
+	r.invalidateID(docID)
+	return remaining, err
+}
+
+// Update updates a URL and evicts it from the cache.
+func (r *CachedURLRepository) Update(ctx context.Context, url model.URL, change model.URLChange) error {
+	err := r.next.Update(ctx, url, change)
+	r.invalidateID(url.ID)
+	return err
+}
+
+// ListHistory delegates to the wrapped repository.
+func (r *CachedURLRepository) ListHistory(ctx context.Context, docID string) ([]model.URLChange, error) {
+	return r.next.ListHistory(ctx, docID)
+}
+
+// Delete removes a URL and evicts it from the cache.
+func (r *CachedURLRepository) Delete(ctx context.Context, docID string) error {
+	err := r.next.Delete(ctx, docID)
+	r.invalidateID(docID)
+	return err
+}
+
+// ListExpired delegates to the wrapped repository.
+func (r *CachedURLRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	return r.next.ListExpired(ctx, before, limit)
+}
+
+// Archive archives a URL and evicts it from the cache.
+func (r *CachedURLRepository) Archive(ctx context.Context, docID string, at time.Time) error {
+	err := r.next.Archive(ctx, docID, at)
+	r.invalidateID(docID)
+	return err
+}
+
+// Trash moves a URL to the trash and evicts it from the cache.
+func (r *CachedURLRepository) Trash(ctx context.Context, docID string, at time.Time) error {
+	err := r.next.Trash(ctx, docID, at)
+	r.invalidateID(docID)
+	return err
+}
+
+// Restore takes a URL out of the trash and evicts it from the cache.
+func (r *CachedURLRepository) Restore(ctx context.Context, docID string, at time.Time) error {
+	err := r.next.Restore(ctx, docID, at)
+	r.invalidateID(docID)
+	return err
+}
+
+// ListTrash delegates to the wrapped repository.
+func (r *CachedURLRepository) ListTrash(ctx context.Context, limit, offset int) ([]model.URL, int, error) {
+	return r.next.ListTrash(ctx, limit, offset)
+}
+
+// ListTrashedBefore delegates to the wrapped repository.
+func (r *CachedURLRepository) ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	return r.next.ListTrashedBefore(ctx, before, limit)
+}
+
+// lookup returns the cached URL for shortCode. ok is false on a miss; found
+// is false when the code is cached as unknown.
+func (r *CachedURLRepository) lookup(shortCode string) (url *model.URL, found, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	elem, exists := r.entries[shortCode]
+	if !exists {
+		return nil, false, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		r.removeLocked(shortCode)
+		return nil, false, false
+	}
+
+	r.lru.MoveToFront(elem)
+	if entry.url == nil {
+		return nil, false, true
+	}
+
+	copied := *entry.url
+	return &copied, true, true
+}
+
+func (r *CachedURLRepository) store(gen uint64, shortCode string, url *model.URL, ttl time.Duration) {
+	if ttl <= 0 || r.config.Size <= 0 {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if gen != r.gen {
+		return
+	}
+
+	r.removeLocked(shortCode)
+
+	entry := &cacheEntry{shortCode: shortCode, url: url, expiresAt: time.Now().Add(ttl)}
+	r.entries[shortCode] = r.lru.PushFront(entry)
+	if url != nil {
+		r.codes[url.ID] = shortCode
+	}
+
+	for r.lru.Len() > r.config.Size {
+		oldest := r.lru.Back().Value.(*cacheEntry)
+		r.removeLocked(oldest.shortCode)
+	}
+}
+
+func (r *CachedURLRepository) invalidateID(docID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gen++
+	if shortCode, ok := r.codes[docID]; ok {
+		r.removeLocked(shortCode)
+	}
+}
+
+func (r *CachedURLRepository) removeLocked(shortCode string) {
+	elem, ok := r.entries[shortCode]
+	if !ok {
+		return
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if entry.url != nil {
+		delete(r.codes, entry.url.ID)
+	}
+	delete(r.entries, shortCode)
+	r.lru.Remove(elem)
+}
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+// countingRepo counts short code lookups that reach the wrapped repository
+// and holds each one until release is closed, when release is set.
+type countingRepo struct {
+	URLRepository
+	lookups atomic.Int32
+	release chan struct{}
+}
+
+func (r *countingRepo) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	r.lookups.Add(1)
+	if r.release != nil {
+		<-r.release
+	}
+	return r.URLRepository.GetByShortCode(ctx, shortCode)
+}
+
+func newTestCache(size int) (*CachedURLRepository, *countingRepo) {
+	backend := &countingRepo{URLRepository: NewMemoryURLRepository()}
+	cache := NewCachedURLRepository(backend, CacheConfig{
+		Size:        size,
+		TTL:         time.Minute,
+		NegativeTTL: time.Minute,
+	})
+	return cache, backend
+}
+
+func TestCachedURLRepositoryServesRepeatLookups(t *testing.T) {
+	cache, backend := newTestCache(10)
+	createTestURL(t, cache, "cached", time.Now())
+
+	for range 3 {
+		url, err := cache.GetByShortCode(context.Background(), "cached")
+		if err != nil || url.ShortCode != "cached" {
+			t.Fatalf("GetByShortCode = %+v, %v", url, err)
+		}
+	}
+	if n := backend.lookups.Load(); n != 1 {
+		t.Fatalf("backend served %d lookups, want 1", n)
+	}
+}
+
+func TestCachedURLRepositoryCoalescesConcurrentMisses(t *testing.T) {
+	cache, backend := newTestCache(10)
+	createTestURL(t, cache, "popular", time.Now())
+	backend.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cache.GetByShortCode(context.Background(), "popular")
+			errs <- err
+		}()
+	}
+	// Give every goroutine time to join the lookup in flight.
+	time.Sleep(50 * time.Millisecond)
+	close(backend.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("GetByShortCode error = %v", err)
+		}
+	}
+	if n := backend.lookups.Load(); n != 1 {
+		t.Fatalf("backend served %d lookups, want 1", n)
+	}
+}
+
+func TestCachedURLRepositoryCachesUnknownCodes(t *testing.T) {
+	cache, backend := newTestCache(10)
+
+	for range 2 {
+		if _, err := cache.GetByShortCode(context.Background(), "later"); !errors.Is(err, ErrURLNotFound) {
+			t.Fatalf("GetByShortCode error = %v, want ErrURLNotFound", err)
+		}
+	}
+	if n := backend.lookups.Load(); n != 1 {
+		t.Fatalf("backend served %d lookups, want 1", n)
+	}
+
+	createTestURL(t, cache, "later", time.Now())
+	if _, err := cache.GetByShortCode(context.Background(), "later"); err != nil {
+		t.Fatalf("GetByShortCode after Create error = %v", err)
+	}
+}
+
+func TestCachedURLRepositoryInvalidatesOnWrite(t *testing.T) {
+	cache, _ := newTestCache(10)
+	createTestURL(t, cache, "changing", time.Now())
+
+	url, err := cache.GetByShortCode(context.Background(), "changing")
+	if err != nil {
+		t.Fatalf("GetByShortCode error = %v", err)
+	}
+	url.OriginalURL = "https://example.com/updated"
+	url.Version++
+	if err := cache.Update(context.Background(), *url, model.URLChange{Version: url.Version}); err != nil {
+		t.Fatalf("Update error = %v", err)
+	}
+	url, err = cache.GetByShortCode(context.Background(), "changing")
+	if err != nil || url.OriginalURL != "https://example.com/updated" {
+		t.Fatalf("GetByShortCode after Update = %+v, %v, want the updated URL", url, err)
+	}
+
+	if err := cache.Delete(context.Background(), url.ID); err != nil {
+		t.Fatalf("Delete error = %v", err)
+	}
+	if _, err := cache.GetByShortCode(context.Background(), "changing"); !errors.Is(err, ErrURLNotFound) {
+		t.Fatalf("GetByShortCode after Delete error = %v, want ErrURLNotFound", err)
+	}
+}
+
+func TestCachedURLRepositoryEvictsLeastRecentlyUsed(t *testing.T) {
+	cache, backend := newTestCache(2)
+	for _, code := range []string{"a", "b", "c"} {
+		createTestURL(t, cache, code, time.Now())
+	}
+
+	for _, code := range []string{"a", "b", "a", "c", "a", "b"} {
+		if _, err := cache.GetByShortCode(context.Background(), code); err != nil {
+			t.Fatalf("GetByShortCode(%s) error = %v", code, err)
+		}
+	}
+	// a, b and c miss once each; c evicts b, so the last b misses again.
+	if n := backend.lookups.Load(); n != 4 {
+		t.Fatalf("backend served %d lookups, want 4", n)
+	}
+}
@@ -0,0 +1,128 @@
+// Package repository provides construction of repositories for the configured backend.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/abhisheksharm-3/shrtn/internal/config"
+)
+
+// Store bundles the repositories of the configured storage backend.
+type Store struct {
+	URLs      URLRepository
+	Analytics AnalyticsRepository
+	closeFn   func() error
+}
+
+// Close releases connections held by the backend.
+func (s *Store) Close() error {
+	if s.closeFn == nil {
+		return nil
+	}
+	return s.closeFn()
+}
+
+// NewStore creates the repositories for cfg.StorageBackend, applying pending
+// migrations first when cfg.AutoMigrate is set. Remote backends get a
+// read-through cache in front of URL lookups when cfg.CacheSize is positive.
+func NewStore(ctx context.Context, cfg *config.Config) (*Store, error) {
+	store, err := newBackendStore(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.CacheSize > 0 && cfg.StorageBackend != config.StorageMemory {
+		store.URLs = NewCachedURLRepository(store.URLs, CacheConfig{
+			Size:        cfg.CacheSize,
+			TTL:         cfg.CacheTTL,
+			NegativeTTL: cfg.CacheNegativeTTL,
+		})
+	}
+
+	return store, nil
+}
+
+func newBackendStore(ctx context.Context, cfg *config.Config) (*Store, error) {
+	switch cfg.StorageBackend {
+	case config.StorageAppwrite:
+		return &Store{
+			URLs:      NewAppwriteURLRepository(cfg),
+			Analytics: NewAppwriteAnalyticsRepository(cfg),
+		}, nil
+	case config.StorageMemory:
+		urls := NewMemoryURLRepository()
+		return &Store{
+			URLs:      urls,
+			Analytics: NewMemoryAnalyticsRepository(urls),
+		}, nil
+	case config.StoragePostgres:
+		db, err := openSQL(ctx, cfg, OpenPostgres, MigratePostgres)
+		if err != nil {
+			return nil, err
+		}
+		return &Store{
+			URLs:      NewPostgresURLRepository(db),
+			Analytics: NewPostgresAnalyticsRepository(db),
+			closeFn:   db.Close,
+		}, nil
+	case config.StorageSQLite:
+		db, err := openSQL(ctx, cfg, OpenSQLite, MigrateSQLite)
+		if err != nil {
+			return nil, err
+		}
+		return &Store{
+			URLs:      NewSQLiteURLRepository(db),
+			Analytics: NewSQLiteAnalyticsRepository(db),
+			closeFn:   db.Close,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported storage backend %q", cfg.StorageBackend)
+	}
+}
+
+// Migrate applies pending schema migrations for cfg.StorageBackend.
+func Migrate(ctx context.Context, cfg *config.Config) error {
+	var (
+		open    func(*config.Config) (*sql.DB, error)
+		migrate func(context.Context, *sql.DB) error
+	)
+	switch cfg.StorageBackend {
+	case config.StoragePostgres:
+		open, migrate = OpenPostgres, MigratePostgres
+	case config.StorageSQLite:
+		open, migrate = OpenSQLite, MigrateSQLite
+	default:
+		return fmt.Errorf("storage backend %q does not use migrations", cfg.StorageBackend)
+	}
+
+	db, err := open(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	return migrate(ctx, db)
+}
+
+func openSQL(
+	ctx context.Context,
+	cfg *config.Config,
+	open func(*config.Config) (*sql.DB, error),
+	migrate func(context.Context, *sql.DB) error,
+) (*sql.DB, error) {
+	db, err := open(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.AutoMigrate {
+		if err := migrate(ctx, db); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	return db, nil
+}
@@ -3,21 +3,59 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/abhisheksharm-3/shrtn/internal/model"
 )
 
+var (
+	ErrURLNotFound        = errors.New("url not found")
+	ErrDuplicateShortCode = errors.New("short code already exists")
+	ErrClickLimitReached  = errors.New("click limit reached")
+	ErrVersionConflict    = errors.New("url was modified concurrently")
+)
+
 // URLRepository defines operations for URL persistence.
+// Create must fail with ErrDuplicateShortCode when the short code is already
+// stored, atomically with respect to concurrent Create calls. ListExpired
+// returns unarchived URLs whose ExpiresAt is at or before the given time.
+// ConsumeClick adds one click to a URL with a click limit only while the
+// count is below MaxClicks, atomically, and returns the clicks remaining
+// afterwards or ErrClickLimitReached.
+// Update stores the mutable fields of url and appends change to its history,
+// provided the stored version is still url.Version-1; otherwise it fails
+// with ErrVersionConflict. Click counts are never overwritten by Update.
+// Trash sets DeletedAt and Restore clears it; both fail with ErrURLNotFound
+// when the URL is not in the expected state. GetAll and ListExpired skip
+// trashed URLs, while GetByShortCode still returns them.
 type URLRepository interface {
 	Create(ctx context.Context, url model.URL) (string, error)
 	GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error)
 	GetAll(ctx context.Context, limit, offset int) ([]model.URL, int, error)
-	UpdateClicks(ctx context.Context, docID string, clicks int) error
+	IncrementClicks(ctx context.Context, docID string, delta int) error
+	ConsumeClick(ctx context.Context, docID string) (int, error)
 	Delete(ctx context.Context, docID string) error
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]model.URL, error)
+	Archive(ctx context.Context, docID string, at time.Time) error
+	Trash(ctx context.Context, docID string, at time.Time) error
+	Restore(ctx context.Context, docID string, at time.Time) error
+	ListTrash(ctx context.Context, limit, offset int) ([]model.URL, int, error)
+	ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]model.URL, error)
+	Update(ctx context.Context, url model.URL, change model.URLChange) error
+	ListHistory(ctx context.Context, docID string) ([]model.URLChange, error)
 }
 
 // AnalyticsRepository defines operations for analytics persistence.
+// CreateBatch returns how many leading entries were persisted, so callers can
+// retry only the remainder when it fails part way. Entries whose URL no
+// longer exists are dropped and count as persisted, so clicks still queued
+// or spooled when a link is purged do not outlive it. CountByVariant counts the
+// entries of a URL recorded for one A/B variant.
 type AnalyticsRepository interface {
 	Create(ctx context.Context, entry model.AnalyticsEntry) (string, error)
+	CreateBatch(ctx context.Context, entries []model.AnalyticsEntry) (int, error)
 	GetByURLID(ctx context.Context, urlID string, limit, offset int) ([]model.AnalyticsEntry, error)
+	CountByVariant(ctx context.Context, urlID, variant string) (int, error)
+	DeleteByURLID(ctx context.Context, urlID string) error
 }
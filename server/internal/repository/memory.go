@@ -0,0 +1,443 @@
+// Package repository provides an in-memory implementation for data persistence.
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+// MemoryURLRepository implements URLRepository using an in-process map.
+type MemoryURLRepository struct {
+	mu      sync.RWMutex
+	byID    map[string]*model.URL
+	byCode  map[string]string
+	history map[string][]model.URLChange
+}
+
+// NewMemoryURLRepository creates a new in-memory URL repository.
+func NewMemoryURLRepository() *MemoryURLRepository {
+	return &MemoryURLRepository{
+		byID:    make(map[string]*model.URL),
+		byCode:  make(map[string]string),
+		history: make(map[string][]model.URLChange),
+	}
+}
+
+// Create stores a new URL and returns its generated ID.
+func (r *MemoryURLRepository) Create(ctx context.Context, url model.URL) (string, error) {
+	if url.ShortCode == "" {
+		return "", fmt.Errorf("short code cannot be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.byCode[url.ShortCode]; exists {
+		return "", ErrDuplicateShortCode
+	}
+
+	docID, err := newID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	url.ID = docID
+	r.byID[docID] = &url
+	r.byCode[url.ShortCode] = docID
+
+	return docID, nil
+}
+
+// GetByShortCode retrieves a URL by its short code.
+func (r *MemoryURLRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	if shortCode == "" {
+		return nil, fmt.Errorf("short code cannot be empty")
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	docID, ok := r.byCode[shortCode]
+	if !ok {
+		return nil, ErrURLNotFound
+	}
+
+	url := *r.byID[docID]
+	return &url, nil
+}
+
+// GetAll retrieves paginated URLs ordered by CreatedAt descending and the total count.
+func (r *MemoryURLRepository) GetAll(ctx context.Context, limit, offset int) ([]model.URL, int, error) {
+	r.mu.RLock()
+	all := make([]model.URL, 0, len(r.byID))
+	for _, url := range r.byID {
+		if url.DeletedAt == nil {
+			all = append(all, *url)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].ID > all[j].ID
+		}
+		return all[i].CreatedAt.After(all[j].CreatedAt)
+	})
+
+	return paginate(all, limit, offset), len(all), nil
+}
+
+// IncrementClicks adds delta to the click count for a URL.
+func (r *MemoryURLRepository) IncrementClicks(ctx context.Context, docID string, delta int) error {
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.byID[docID]
+	if !ok {
+		return ErrURLNotFound
+	}
+
+	url.Clicks += delta
+	url.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// ConsumeClick increments the click count of a click-limited URL while it
+// is below MaxClicks.
+func (r *MemoryURLRepository) ConsumeClick(ctx context.Context, docID string) (int, error) {
+	if docID == "" {
+		return 0, fmt.Errorf("document ID cannot be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.byID[docID]
+	if !ok {
+		return 0, ErrURLNotFound
+	}
+	if url.MaxClicks <= 0 || url.Clicks >= url.MaxClicks {
+		return 0, ErrClickLimitReached
+	}
+
+	url.Clicks++
+	url.UpdatedAt = time.Now().UTC()
+	return url.MaxClicks - url.Clicks, nil
+}
+
+// Update stores the mutable fields of url and records change.
+func (r *MemoryURLRepository) Update(ctx context.Context, url model.URL, change model.URLChange) error {
+	if url.ID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.byID[url.ID]
+	if !ok {
+		return ErrURLNotFound
+	}
+	if stored.Version != url.Version-1 {
+		return ErrVersionConflict
+	}
+
+	changeID, err := newID()
+	if err != nil {
+		return fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	stored.OriginalURL = url.OriginalURL
+	stored.UpdatedAt = url.UpdatedAt
+	stored.ExpiresAt = url.ExpiresAt
+	stored.ArchivedAt = url.ArchivedAt
+	stored.ActiveFrom = url.ActiveFrom
+	stored.Timezone = url.Timezone
+	stored.Disabled = url.Disabled
+	stored.MaxClicks = url.MaxClicks
+	stored.PasswordHash = url.PasswordHash
+	stored.Version = url.Version
+	stored.RedirectStatus = url.RedirectStatus
+	stored.ForwardQuery = url.ForwardQuery
+	stored.ForwardPath = url.ForwardPath
+	stored.Rules = slices.Clone(url.Rules)
+	stored.Variants = slices.Clone(url.Variants)
+
+	change.ID = changeID
+	change.URLId = url.ID
+	r.history[url.ID] = append(r.history[url.ID], change)
+	return nil
+}
+
+// ListHistory returns the recorded changes of a URL, newest first.
+func (r *MemoryURLRepository) ListHistory(ctx context.Context, docID string) ([]model.URLChange, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	recorded := r.history[docID]
+	changes := make([]model.URLChange, 0, len(recorded))
+	for i := len(recorded) - 1; i >= 0; i-- {
+		changes = append(changes, recorded[i])
+	}
+	return changes, nil
+}
+
+// exists reports whether a URL with docID is stored.
+func (r *MemoryURLRepository) exists(docID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.byID[docID]
+	return ok
+}
+
+// Delete removes a URL by ID.
+func (r *MemoryURLRepository) Delete(ctx context.Context, docID string) error {
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.byID[docID]
+	if !ok {
+		return ErrURLNotFound
+	}
+
+	delete(r.byCode, url.ShortCode)
+	delete(r.byID, docID)
+	delete(r.history, docID)
+	return nil
+}
+
+// ListExpired returns unarchived URLs that expired at or before before.
+func (r *MemoryURLRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expired := make([]model.URL, 0)
+	for _, url := range r.byID {
+		if url.ArchivedAt == nil && url.DeletedAt == nil && url.ExpiresAt != nil && !url.ExpiresAt.After(before) {
+			expired = append(expired, *url)
+		}
+	}
+
+	sort.Slice(expired, func(i, j int) bool {
+		return expired[i].ExpiresAt.Before(*expired[j].ExpiresAt)
+	})
+	if limit > 0 && len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+// Archive marks a URL as archived at the given time.
+func (r *MemoryURLRepository) Archive(ctx context.Context, docID string, at time.Time) error {
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.byID[docID]
+	if !ok {
+		return ErrURLNotFound
+	}
+
+	archivedAt := at.UTC()
+	url.ArchivedAt = &archivedAt
+	url.UpdatedAt = archivedAt
+	return nil
+}
+
+// Trash moves a URL to the trash.
+func (r *MemoryURLRepository) Trash(ctx context.Context, docID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.byID[docID]
+	if !ok || url.DeletedAt != nil {
+		return ErrURLNotFound
+	}
+
+	deletedAt := at.UTC()
+	url.DeletedAt = &deletedAt
+	url.UpdatedAt = deletedAt
+	return nil
+}
+
+// Restore takes a URL out of the trash.
+func (r *MemoryURLRepository) Restore(ctx context.Context, docID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	url, ok := r.byID[docID]
+	if !ok || url.DeletedAt == nil {
+		return ErrURLNotFound
+	}
+
+	url.DeletedAt = nil
+	url.UpdatedAt = at.UTC()
+	return nil
+}
+
+// ListTrash returns paginated trashed URLs, most recently deleted first, and
+// their total count.
+func (r *MemoryURLRepository) ListTrash(ctx context.Context, limit, offset int) ([]model.URL, int, error) {
+	trashed := r.trashed()
+	sort.Slice(trashed, func(i, j int) bool {
+		return trashed[i].DeletedAt.After(*trashed[j].DeletedAt)
+	})
+	return paginate(trashed, limit, offset), len(trashed), nil
+}
+
+// ListTrashedBefore returns URLs trashed at or before before, oldest first.
+func (r *MemoryURLRepository) ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	trashed := slices.DeleteFunc(r.trashed(), func(url model.URL) bool {
+		return url.DeletedAt.After(before)
+	})
+	sort.Slice(trashed, func(i, j int) bool {
+		return trashed[i].DeletedAt.Before(*trashed[j].DeletedAt)
+	})
+	if limit > 0 && len(trashed) > limit {
+		trashed = trashed[:limit]
+	}
+	return trashed, nil
+}
+
+func (r *MemoryURLRepository) trashed() []model.URL {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	trashed := make([]model.URL, 0)
+	for _, url := range r.byID {
+		if url.DeletedAt != nil {
+			trashed = append(trashed, *url)
+		}
+	}
+	return trashed
+}
+
+// MemoryAnalyticsRepository implements AnalyticsRepository using an in-process map.
+type MemoryAnalyticsRepository struct {
+	urls *MemoryURLRepository
+
+	mu      sync.RWMutex
+	byURLID map[string][]model.AnalyticsEntry
+}
+
+// NewMemoryAnalyticsRepository creates a new in-memory analytics repository
+// for the URLs stored in urls.
+func NewMemoryAnalyticsRepository(urls *MemoryURLRepository) *MemoryAnalyticsRepository {
+	return &MemoryAnalyticsRepository{
+		urls:    urls,
+		byURLID: make(map[string][]model.AnalyticsEntry),
+	}
+}
+
+// Create stores a new analytics entry and returns its generated ID.
+func (r *MemoryAnalyticsRepository) Create(ctx context.Context, entry model.AnalyticsEntry) (string, error) {
+	if entry.URLId == "" {
+		return "", fmt.Errorf("URL ID cannot be empty for analytics entry")
+	}
+
+	entryID, err := newID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	entry.ID = entryID
+
+	r.mu.Lock()
+	r.byURLID[entry.URLId] = append(r.byURLID[entry.URLId], entry)
+	r.mu.Unlock()
+
+	return entryID, nil
+}
+
+// CreateBatch stores all entries whose URL still exists.
+func (r *MemoryAnalyticsRepository) CreateBatch(ctx context.Context, entries []model.AnalyticsEntry) (int, error) {
+	for i, entry := range entries {
+		if entry.URLId != "" && !r.urls.exists(entry.URLId) {
+			continue
+		}
+		if _, err := r.Create(ctx, entry); err != nil {
+			return i, err
+		}
+	}
+	return len(entries), nil
+}
+
+// GetByURLID retrieves analytics entries for a URL ordered by timestamp descending.
+func (r *MemoryAnalyticsRepository) GetByURLID(ctx context.Context, urlID string, limit, offset int) ([]model.AnalyticsEntry, error) {
+	if urlID == "" {
+		return nil, fmt.Errorf("URL ID cannot be empty")
+	}
+
+	r.mu.RLock()
+	entries := make([]model.AnalyticsEntry, len(r.byURLID[urlID]))
+	copy(entries, r.byURLID[urlID])
+	r.mu.RUnlock()
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.After(entries[j].Timestamp)
+	})
+
+	return paginate(entries, limit, offset), nil
+}
+
+// CountByVariant counts the analytics entries of a URL served variant.
+func (r *MemoryAnalyticsRepository) CountByVariant(ctx context.Context, urlID, variant string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, entry := range r.byURLID[urlID] {
+		if entry.Variant == variant {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// DeleteByURLID removes all analytics entries of a URL.
+func (r *MemoryAnalyticsRepository) DeleteByURLID(ctx context.Context, urlID string) error {
+	r.mu.Lock()
+	delete(r.byURLID, urlID)
+	r.mu.Unlock()
+	return nil
+}
+
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(items) {
+		return []T{}
+	}
+	end := len(items)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return items[offset:end]
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
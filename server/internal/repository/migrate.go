@@ -0,0 +1,172 @@
+// Package repository provides versioned schema migrations for SQL backends.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// sqlDialect describes the differences between SQL backends that matter to
+// the migration runner.
+type sqlDialect struct {
+	name       string
+	dir        string
+	lockSQL    string
+	unlockSQL  string
+	createSQL  string
+	insertSQL  string
+	appliedSQL string
+}
+
+var postgresDialect = sqlDialect{
+	name:      "postgres",
+	dir:       "migrations/postgres",
+	lockSQL:   "SELECT pg_advisory_lock(72616469)",
+	unlockSQL: "SELECT pg_advisory_unlock(72616469)",
+	createSQL: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT        NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL
+	)`,
+	insertSQL:  "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, now())",
+	appliedSQL: "SELECT version FROM schema_migrations",
+}
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+// runMigrations applies every migration in the dialect's directory that has
+// not been recorded in schema_migrations, each inside its own transaction.
+func runMigrations(ctx context.Context, db *sql.DB, dialect sqlDialect) error {
+	migrations, err := loadMigrations(dialect.dir)
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if dialect.lockSQL != "" {
+		if _, err := conn.ExecContext(ctx, dialect.lockSQL); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer conn.ExecContext(context.Background(), dialect.unlockSQL)
+	}
+
+	if _, err := conn.ExecContext(ctx, dialect.createSQL); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, conn, dialect)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := applyMigration(ctx, conn, dialect, m); err != nil {
+			return err
+		}
+		log.Printf("%s: applied migration %04d_%s", dialect.name, m.version, m.name)
+	}
+
+	return nil
+}
+
+func applyMigration(ctx context.Context, conn *sql.Conn, dialect sqlDialect, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return fmt.Errorf("migration %04d_%s failed: %w", m.version, m.name, err)
+	}
+	if _, err := tx.ExecContext(ctx, dialect.insertSQL, m.version, m.name); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	return tx.Commit()
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn, dialect sqlDialect) (map[int]bool, error) {
+	rows, err := conn.QueryContext(ctx, dialect.appliedSQL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("failed to scan migration version: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// loadMigrations reads NNNN_name.sql files from dir, sorted by version.
+func loadMigrations(dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %q must be named NNNN_name.sql", entry.Name())
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %q has invalid version: %w", entry.Name(), err)
+		}
+
+		body, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %q: %w", entry.Name(), err)
+		}
+
+		migrations = append(migrations, migration{version: version, name: name, sql: string(body)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].version == migrations[i-1].version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].version)
+		}
+	}
+
+	return migrations, nil
+}
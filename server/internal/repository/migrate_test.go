@@ -0,0 +1,46 @@
+package repository
+
+import "testing"
+
+func TestLoadMigrations(t *testing.T) {
+	for _, dialect := range []sqlDialect{postgresDialect, sqliteDialect} {
+		t.Run(dialect.name, func(t *testing.T) {
+			migrations, err := loadMigrations(dialect.dir)
+			if err != nil {
+				t.Fatalf("loadMigrations error = %v", err)
+			}
+			if len(migrations) == 0 {
+				t.Fatal("no migrations found")
+			}
+			for i, m := range migrations {
+				if m.version != i+1 {
+					t.Errorf("migration %d_%s has version %d, want %d", m.version, m.name, m.version, i+1)
+				}
+				if m.sql == "" {
+					t.Errorf("migration %04d_%s is empty", m.version, m.name)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrationsMatchAcrossDialects(t *testing.T) {
+	postgres, err := loadMigrations(postgresDialect.dir)
+	if err != nil {
+		t.Fatalf("loadMigrations(postgres) error = %v", err)
+	}
+	sqlite, err := loadMigrations(sqliteDialect.dir)
+	if err != nil {
+		t.Fatalf("loadMigrations(sqlite) error = %v", err)
+	}
+
+	if len(postgres) != len(sqlite) {
+		t.Fatalf("postgres has %d migrations, sqlite %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if postgres[i].version != sqlite[i].version || postgres[i].name != sqlite[i].name {
+			t.Errorf("migration %d is %04d_%s for postgres but %04d_%s for sqlite",
+				i, postgres[i].version, postgres[i].name, sqlite[i].version, sqlite[i].name)
+		}
+	}
+}
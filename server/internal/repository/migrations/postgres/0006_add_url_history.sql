@@ -0,0 +1,14 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+CREATE TABLE IF NOT EXISTS url_history (
+    id             TEXT PRIMARY KEY,
+    url_id         TEXT        NOT NULL REFERENCES urls (id) ON DELETE CASCADE,
+    version        BIGINT      NOT NULL,
+    previous_url   TEXT        NOT NULL,
+    original_url   TEXT        NOT NULL,
+    changed_fields TEXT        NOT NULL DEFAULT '',
+    actor          TEXT        NOT NULL DEFAULT '',
+    changed_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS url_history_url_id_version_key ON url_history (url_id, version);
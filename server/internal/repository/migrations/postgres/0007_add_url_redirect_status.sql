@@ -0,0 +1,2 @@
+-- Existing links keep the 301 they were always served with.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS redirect_status INTEGER NOT NULL DEFAULT 301;
@@ -0,0 +1,10 @@
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_source   TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_medium   TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_campaign TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_term     TEXT NOT NULL DEFAULT '';
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS utm_content  TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE analytics ADD COLUMN IF NOT EXISTS campaign TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS analytics_campaign_timestamp_idx ON analytics (campaign, timestamp DESC)
+    WHERE campaign <> '';
@@ -0,0 +1,2 @@
+-- Targeting rules of a link, as a JSON array; empty when it has none.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS rules TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,3 @@
+-- When a link goes live, and the IANA time zone of its time-window rules.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS active_from TIMESTAMPTZ;
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,2 @@
+-- Disabled links keep their code and analytics but do not redirect.
+ALTER TABLE urls ADD COLUMN IF NOT EXISTS disabled BOOLEAN NOT NULL DEFAULT FALSE;
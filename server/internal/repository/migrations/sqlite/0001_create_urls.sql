@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS urls (
+    id           TEXT PRIMARY KEY,
+    short_code   TEXT    NOT NULL,
+    original_url TEXT    NOT NULL,
+    created_at   TEXT    NOT NULL,
+    updated_at   TEXT    NOT NULL,
+    clicks       INTEGER NOT NULL DEFAULT 0,
+    user_id      TEXT    NOT NULL DEFAULT ''
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS urls_short_code_key ON urls (short_code);
+CREATE INDEX IF NOT EXISTS urls_created_at_idx ON urls (created_at DESC);
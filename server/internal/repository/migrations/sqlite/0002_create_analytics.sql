@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS analytics (
+    id         TEXT PRIMARY KEY,
+    url_id     TEXT NOT NULL,
+    timestamp  TEXT NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    referer    TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS analytics_url_id_timestamp_idx ON analytics (url_id, timestamp DESC);
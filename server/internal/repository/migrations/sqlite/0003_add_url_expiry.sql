@@ -0,0 +1,5 @@
+ALTER TABLE urls ADD COLUMN expires_at TEXT;
+ALTER TABLE urls ADD COLUMN archived_at TEXT;
+
+CREATE INDEX IF NOT EXISTS urls_expires_at_idx ON urls (expires_at)
+    WHERE expires_at IS NOT NULL AND archived_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE urls ADD COLUMN forward_query INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE urls ADD COLUMN forward_path INTEGER NOT NULL DEFAULT 0;
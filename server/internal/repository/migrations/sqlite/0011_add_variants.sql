@@ -0,0 +1,7 @@
+-- Weighted A/B destinations of a link, as a JSON array; empty when it has none.
+ALTER TABLE urls ADD COLUMN variants TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE analytics ADD COLUMN variant TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS analytics_url_id_variant_idx ON analytics (url_id, variant)
+    WHERE variant <> '';
@@ -0,0 +1,3 @@
+-- When a link goes live, and the IANA time zone of its time-window rules.
+ALTER TABLE urls ADD COLUMN active_from TEXT;
+ALTER TABLE urls ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
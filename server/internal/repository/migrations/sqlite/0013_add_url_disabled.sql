@@ -0,0 +1,2 @@
+-- Disabled links keep their code and analytics but do not redirect.
+ALTER TABLE urls ADD COLUMN disabled INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Deleted links stay in the trash, keeping their short code, until purged.
+ALTER TABLE urls ADD COLUMN deleted_at TEXT;
+
+CREATE INDEX IF NOT EXISTS urls_deleted_at_idx ON urls (deleted_at)
+    WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,647 @@
+// Package repository provides PostgreSQL implementation for data persistence.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/config"
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+const pgUniqueViolation = "23505"
+
+// OpenPostgres opens and verifies a connection pool to cfg.DatabaseURL.
+func OpenPostgres(cfg *config.Config) (*sql.DB, error) {
+	db, err := sql.Open("pgx", cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open postgres: %w", err)
+	}
+
+	db.SetMaxOpenConns(cfg.DatabaseMaxConns)
+	db.SetMaxIdleConns(cfg.DatabaseMaxConns)
+	db.SetConnMaxIdleTime(5 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	return db, nil
+}
+
+// MigratePostgres applies pending schema migrations to db.
+func MigratePostgres(ctx context.Context, db *sql.DB) error {
+	return runMigrations(ctx, db, postgresDialect)
+}
+
+// PostgresURLRepository implements URLRepository using PostgreSQL.
+type PostgresURLRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresURLRepository creates a new PostgreSQL URL repository.
+func NewPostgresURLRepository(db *sql.DB) *PostgresURLRepository {
+	return &PostgresURLRepository{db: db}
+}
+
+// Create inserts a new URL row and returns its ID.
+func (r *PostgresURLRepository) Create(ctx context.Context, url model.URL) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	docID, err := newID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	rules, err := encodeRules(url.Rules)
+	if err != nil {
+		return "", err
+	}
+	variants, err := encodeVariants(url.Variants)
+	if err != nil {
+		return "", err
+	}
+
+	utm := utmValue(url.UTM)
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO urls (`+urlColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26)`,
+		docID, url.ShortCode, url.OriginalURL, url.CreatedAt, url.UpdatedAt, url.Clicks, url.UserID,
+		url.ExpiresAt, url.ArchivedAt, url.MaxClicks, url.PasswordHash, url.Version, url.RedirectStatus,
+		url.ForwardQuery, url.ForwardPath, utm.Source, utm.Medium, utm.Campaign, utm.Term, utm.Content, rules, variants,
+		url.ActiveFrom, url.Timezone, url.Disabled, url.DeletedAt,
+	)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return "", ErrDuplicateShortCode
+		}
+		return "", fmt.Errorf("failed to create URL row: %w", err)
+	}
+
+	return docID, nil
+}
+
+// GetByShortCode retrieves a URL by its short code.
+func (r *PostgresURLRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if shortCode == "" {
+		return nil, fmt.Errorf("short code cannot be empty")
+	}
+
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE short_code = $1`,
+		shortCode,
+	)
+
+	url, err := scanURL(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query URL by short code: %w", err)
+	}
+
+	return url, nil
+}
+
+// GetAll retrieves paginated URLs and total count.
+func (r *PostgresURLRepository) GetAll(ctx context.Context, limit, offset int) ([]model.URL, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM urls WHERE deleted_at IS NULL`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count URLs: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE deleted_at IS NULL
+		 ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list URLs: %w", err)
+	}
+	defer rows.Close()
+
+	urls := make([]model.URL, 0, limit)
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan URL row: %w", err)
+		}
+		urls = append(urls, *url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list URLs: %w", err)
+	}
+
+	return urls, total, nil
+}
+
+// IncrementClicks atomically adds delta to the stored click count for a URL.
+func (r *PostgresURLRepository) IncrementClicks(ctx context.Context, docID string, delta int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET clicks = clicks + $2, updated_at = $3 WHERE id = $1`,
+		docID, delta, time.Now().UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update URL clicks: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// ConsumeClick increments the click count of a click-limited URL in a
+// single conditional update.
+func (r *PostgresURLRepository) ConsumeClick(ctx context.Context, docID string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return 0, fmt.Errorf("document ID cannot be empty")
+	}
+
+	var remaining int
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE urls SET clicks = clicks + 1, updated_at = $2
+		 WHERE id = $1 AND max_clicks > 0 AND clicks < max_clicks
+		 RETURNING max_clicks - clicks`,
+		docID, time.Now().UTC(),
+	).Scan(&remaining)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, r.consumeFailure(ctx, docID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to consume URL click: %w", err)
+	}
+
+	return remaining, nil
+}
+
+// consumeFailure explains why ConsumeClick matched no row.
+func (r *PostgresURLRepository) consumeFailure(ctx context.Context, docID string) error {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM urls WHERE id = $1)`, docID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to consume URL click: %w", err)
+	}
+	if !exists {
+		return ErrURLNotFound
+	}
+	return ErrClickLimitReached
+}
+
+// Update stores the mutable fields of url and records change in one
+// transaction.
+func (r *PostgresURLRepository) Update(ctx context.Context, url model.URL, change model.URLChange) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if url.ID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	changeID, err := newID()
+	if err != nil {
+		return fmt.Errorf("failed to generate ID: %w", err)
+	}
+	rules, err := encodeRules(url.Rules)
+	if err != nil {
+		return err
+	}
+	variants, err := encodeVariants(url.Variants)
+	if err != nil {
+		return err
+	}
+
+	return updateURLWithHistory(ctx, r.db,
+		`UPDATE urls SET original_url = $2, updated_at = $3, expires_at = $4, archived_at = $5,
+		 max_clicks = $6, password_hash = $7, version = $8, redirect_status = $9,
+		 forward_query = $10, forward_path = $11, rules = $12, variants = $13,
+		 active_from = $14, timezone = $15, disabled = $16
+		 WHERE id = $1 AND version = $17`,
+		[]any{
+			url.ID, url.OriginalURL, url.UpdatedAt, url.ExpiresAt, url.ArchivedAt,
+			url.MaxClicks, url.PasswordHash, url.Version, url.RedirectStatus,
+			url.ForwardQuery, url.ForwardPath, rules, variants,
+			url.ActiveFrom, url.Timezone, url.Disabled, url.Version - 1,
+		},
+		`INSERT INTO url_history (`+historyColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		[]any{
+			changeID, url.ID, change.Version, change.PreviousURL, change.OriginalURL,
+			joinFields(change.Fields), change.Actor, change.ChangedAt,
+		},
+		`SELECT EXISTS (SELECT 1 FROM urls WHERE id = $1)`,
+		url.ID,
+	)
+}
+
+// ListHistory returns the recorded changes of a URL, newest first.
+func (r *PostgresURLRepository) ListHistory(ctx context.Context, docID string) ([]model.URLChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+historyColumns+`
+		 FROM url_history WHERE url_id = $1 ORDER BY version DESC`,
+		docID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query URL history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := make([]model.URLChange, 0)
+	for rows.Next() {
+		var (
+			change model.URLChange
+			fields string
+		)
+		if err := rows.Scan(
+			&change.ID,
+			&change.URLId,
+			&change.Version,
+			&change.PreviousURL,
+			&change.OriginalURL,
+			&fields,
+			&change.Actor,
+			&change.ChangedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan URL history row: %w", err)
+		}
+		change.Fields = splitFields(fields)
+		change.ChangedAt = change.ChangedAt.UTC()
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query URL history: %w", err)
+	}
+
+	return changes, nil
+}
+
+// Delete removes a URL row by ID.
+func (r *PostgresURLRepository) Delete(ctx context.Context, docID string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM urls WHERE id = $1`, docID)
+	if err != nil {
+		return fmt.Errorf("failed to delete URL row: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// ListExpired returns unarchived URLs that expired at or before before,
+// oldest first.
+func (r *PostgresURLRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE expires_at <= $1 AND archived_at IS NULL AND deleted_at IS NULL
+		 ORDER BY expires_at LIMIT $2`,
+		before, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired URLs: %w", err)
+	}
+	defer rows.Close()
+
+	urls := make([]model.URL, 0)
+	for rows.Next() {
+		url, err := scanURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan URL row: %w", err)
+		}
+		urls = append(urls, *url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list expired URLs: %w", err)
+	}
+
+	return urls, nil
+}
+
+// Archive marks a URL row as archived at the given time.
+func (r *PostgresURLRepository) Archive(ctx context.Context, docID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET archived_at = $2, updated_at = $2 WHERE id = $1`,
+		docID, at.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to archive URL row: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// Trash moves a URL to the trash.
+func (r *PostgresURLRepository) Trash(ctx context.Context, docID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET deleted_at = $1, updated_at = $2 WHERE id = $3 AND deleted_at IS NULL`,
+		at.UTC(), at.UTC(), docID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to trash URL row: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// Restore takes a URL out of the trash.
+func (r *PostgresURLRepository) Restore(ctx context.Context, docID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET deleted_at = NULL, updated_at = $1 WHERE id = $2 AND deleted_at IS NOT NULL`,
+		at.UTC(), docID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore URL row: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// ListTrash returns paginated trashed URLs, most recently deleted first, and
+// their total count.
+func (r *PostgresURLRepository) ListTrash(ctx context.Context, limit, offset int) ([]model.URL, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM urls WHERE deleted_at IS NOT NULL`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count trashed URLs: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE deleted_at IS NOT NULL
+		 ORDER BY deleted_at DESC, id DESC LIMIT $1 OFFSET $2`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list trashed URLs: %w", err)
+	}
+
+	urls, err := collectURLs(rows, scanURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list trashed URLs: %w", err)
+	}
+	return urls, total, nil
+}
+
+// ListTrashedBefore returns URLs trashed at or before before, oldest first.
+func (r *PostgresURLRepository) ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE deleted_at <= $1
+		 ORDER BY deleted_at LIMIT $2`,
+		before.UTC(), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed URLs: %w", err)
+	}
+
+	urls, err := collectURLs(rows, scanURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed URLs: %w", err)
+	}
+	return urls, nil
+}
+
+// PostgresAnalyticsRepository implements AnalyticsRepository using PostgreSQL.
+type PostgresAnalyticsRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresAnalyticsRepository creates a new PostgreSQL analytics repository.
+func NewPostgresAnalyticsRepository(db *sql.DB) *PostgresAnalyticsRepository {
+	return &PostgresAnalyticsRepository{db: db}
+}
+
+// Create inserts a new analytics entry and returns its ID.
+func (r *PostgresAnalyticsRepository) Create(ctx context.Context, entry model.AnalyticsEntry) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if entry.URLId == "" {
+		return "", fmt.Errorf("URL ID cannot be empty for analytics entry")
+	}
+
+	entryID, err := newID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO analytics (`+analyticsColumns+`)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		entryID, entry.URLId, entry.Timestamp, entry.UserAgent, entry.IPAddress, entry.Referer, entry.Campaign, entry.Variant,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create analytics entry: %w", err)
+	}
+
+	return entryID, nil
+}
+
+// CreateBatch inserts all entries whose URL still exists in a single
+// transaction.
+func (r *PostgresAnalyticsRepository) CreateBatch(ctx context.Context, entries []model.AnalyticsEntry) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	err := insertAnalyticsBatch(ctx, r.db, entries,
+		`INSERT INTO analytics (`+analyticsColumns+`)
+		 SELECT $1::text, $2::text, $3::timestamptz, $4::text, $5::text, $6::text, $7::text, $8::text
+		 WHERE EXISTS (SELECT 1 FROM urls WHERE id = $2)`,
+		func(entryID string, entry model.AnalyticsEntry) []any {
+			return []any{entryID, entry.URLId, entry.Timestamp, entry.UserAgent, entry.IPAddress, entry.Referer, entry.Campaign, entry.Variant}
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// GetByURLID retrieves analytics entries for a URL with pagination.
+func (r *PostgresAnalyticsRepository) GetByURLID(ctx context.Context, urlID string, limit, offset int) ([]model.AnalyticsEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if urlID == "" {
+		return nil, fmt.Errorf("URL ID cannot be empty")
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+analyticsColumns+`
+		 FROM analytics WHERE url_id = $1
+		 ORDER BY timestamp DESC LIMIT $2 OFFSET $3`,
+		urlID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analytics: %w", err)
+	}
+	defer rows.Close()
+
+	return scanAnalyticsEntries(rows)
+}
+
+// CountByVariant counts the analytics entries of a URL served variant.
+func (r *PostgresAnalyticsRepository) CountByVariant(ctx context.Context, urlID, variant string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM analytics WHERE url_id = $1 AND variant = $2`,
+		urlID, variant,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count analytics: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteByURLID removes all analytics entries of a URL.
+func (r *PostgresAnalyticsRepository) DeleteByURLID(ctx context.Context, urlID string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM analytics WHERE url_id = $1`, urlID); err != nil {
+		return fmt.Errorf("failed to delete analytics: %w", err)
+	}
+	return nil
+}
+
+func scanURL(row rowScanner) (*model.URL, error) {
+	var (
+		url                                          model.URL
+		expiresAt, archivedAt, activeFrom, deletedAt sql.NullTime
+		utm                                          model.UTM
+		rules, variants                              string
+	)
+	if err := row.Scan(
+		&url.ID,
+		&url.ShortCode,
+		&url.OriginalURL,
+		&url.CreatedAt,
+		&url.UpdatedAt,
+		&url.Clicks,
+		&url.UserID,
+		&expiresAt,
+		&archivedAt,
+		&url.MaxClicks,
+		&url.PasswordHash,
+		&url.Version,
+		&url.RedirectStatus,
+		&url.ForwardQuery,
+		&url.ForwardPath,
+		&utm.Source,
+		&utm.Medium,
+		&utm.Campaign,
+		&utm.Term,
+		&utm.Content,
+		&rules,
+		&variants,
+		&activeFrom,
+		&url.Timezone,
+		&url.Disabled,
+		&deletedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	decodedRules, err := decodeRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	decodedVariants, err := decodeVariants(variants)
+	if err != nil {
+		return nil, err
+	}
+
+	url.CreatedAt = url.CreatedAt.UTC()
+	url.UpdatedAt = url.UpdatedAt.UTC()
+	url.ExpiresAt = nullTimePtr(expiresAt)
+	url.ArchivedAt = nullTimePtr(archivedAt)
+	url.ActiveFrom = nullTimePtr(activeFrom)
+	url.DeletedAt = nullTimePtr(deletedAt)
+	url.UTM = utmPtr(utm)
+	url.Rules = decodedRules
+	url.Variants = decodedVariants
+	return &url, nil
+}
+
+func scanAnalyticsEntries(rows *sql.Rows) ([]model.AnalyticsEntry, error) {
+	entries := make([]model.AnalyticsEntry, 0)
+	for rows.Next() {
+		var entry model.AnalyticsEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.URLId,
+			&entry.Timestamp,
+			&entry.UserAgent,
+			&entry.IPAddress,
+			&entry.Referer,
+			&entry.Campaign,
+			&entry.Variant,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan analytics row: %w", err)
+		}
+		entry.Timestamp = entry.Timestamp.UTC()
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query analytics: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/abhisheksharm-3/shrtn/internal/config"
+)
+
+// openTestPostgres connects to TEST_DATABASE_URL, skipping the test when it
+// is unset. The database's tables are emptied, so it must be disposable.
+func openTestPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := OpenPostgres(&config.Config{DatabaseURL: dsn, DatabaseMaxConns: 10})
+	if err != nil {
+		t.Fatalf("OpenPostgres error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigratePostgres(t *testing.T) {
+	db := openTestPostgres(t)
+	migrations, err := loadMigrations(postgresDialect.dir)
+	if err != nil {
+		t.Fatalf("loadMigrations error = %v", err)
+	}
+
+	for run := 1; run <= 2; run++ {
+		if err := MigratePostgres(context.Background(), db); err != nil {
+			t.Fatalf("run %d: MigratePostgres error = %v", run, err)
+		}
+		var applied int
+		if err := db.QueryRow("SELECT count(*) FROM schema_migrations").Scan(&applied); err != nil {
+			t.Fatalf("failed to count migrations: %v", err)
+		}
+		if applied != len(migrations) {
+			t.Fatalf("run %d: %d migrations recorded, want %d", run, applied, len(migrations))
+		}
+	}
+}
+
+func TestPostgresStore(t *testing.T) {
+	db := openTestPostgres(t)
+	if err := MigratePostgres(context.Background(), db); err != nil {
+		t.Fatalf("MigratePostgres error = %v", err)
+	}
+
+	testStore(t, func(t *testing.T) (URLRepository, AnalyticsRepository) {
+		if _, err := db.Exec("TRUNCATE urls, analytics, url_history"); err != nil {
+			t.Fatalf("failed to empty tables: %v", err)
+		}
+		return NewPostgresURLRepository(db), NewPostgresAnalyticsRepository(db)
+	})
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+// testStore runs the behaviour every backend must share against the
+// repositories returned by newStore, which is called once per subtest.
+func testStore(t *testing.T, newStore func(t *testing.T) (URLRepository, AnalyticsRepository)) {
+	t.Run("short codes are unique", func(t *testing.T) {
+		urls, _ := newStore(t)
+		createTestURL(t, urls, "taken", time.Now())
+
+		_, err := urls.Create(context.Background(), testURL("taken", time.Now()))
+		if !errors.Is(err, ErrDuplicateShortCode) {
+			t.Fatalf("second Create error = %v, want ErrDuplicateShortCode", err)
+		}
+	})
+
+	t.Run("concurrent creates claim a code once", func(t *testing.T) {
+		urls, _ := newStore(t)
+
+		const n = 8
+		var wg sync.WaitGroup
+		errs := make([]error, n)
+		for i := range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = urls.Create(context.Background(), testURL("race", time.Now()))
+			}()
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				created++
+			case !errors.Is(err, ErrDuplicateShortCode):
+				t.Fatalf("Create error = %v, want nil or ErrDuplicateShortCode", err)
+			}
+		}
+		if created != 1 {
+			t.Fatalf("%d creates succeeded, want 1", created)
+		}
+	})
+
+	t.Run("get by short code", func(t *testing.T) {
+		urls, _ := newStore(t)
+		id := createTestURL(t, urls, "found", time.Now())
+
+		url, err := urls.GetByShortCode(context.Background(), "found")
+		if err != nil {
+			t.Fatalf("GetByShortCode error = %v", err)
+		}
+		if url.ID != id || url.OriginalURL != "https://example.com/found" {
+			t.Fatalf("GetByShortCode = %+v, want ID %s", url, id)
+		}
+		if _, err := urls.GetByShortCode(context.Background(), "missing"); !errors.Is(err, ErrURLNotFound) {
+			t.Fatalf("GetByShortCode(missing) error = %v, want ErrURLNotFound", err)
+		}
+	})
+
+	t.Run("get all orders by creation time", func(t *testing.T) {
+		urls, _ := newStore(t)
+		base := time.Now().UTC().Truncate(time.Second)
+		for code, minutes := range map[string]int{"first": 1, "second": 2, "third": 3, "fourth": 4} {
+			createTestURL(t, urls, code, base.Add(time.Duration(minutes)*time.Minute))
+		}
+		trashed := createTestURL(t, urls, "trashed", base.Add(time.Hour))
+		if err := urls.Trash(context.Background(), trashed, base); err != nil {
+			t.Fatalf("Trash error = %v", err)
+		}
+
+		page, total, err := urls.GetAll(context.Background(), 2, 1)
+		if err != nil {
+			t.Fatalf("GetAll error = %v", err)
+		}
+		if total != 4 {
+			t.Errorf("total = %d, want 4", total)
+		}
+		if got := shortCodes(page); fmt.Sprint(got) != "[third second]" {
+			t.Errorf("GetAll(2, 1) = %v, want [third second]", got)
+		}
+	})
+
+	t.Run("increment clicks", func(t *testing.T) {
+		urls, _ := newStore(t)
+		id := createTestURL(t, urls, "clicks", time.Now())
+
+		for _, delta := range []int{3, 4} {
+			if err := urls.IncrementClicks(context.Background(), id, delta); err != nil {
+				t.Fatalf("IncrementClicks error = %v", err)
+			}
+		}
+		url, err := urls.GetByShortCode(context.Background(), "clicks")
+		if err != nil {
+			t.Fatalf("GetByShortCode error = %v", err)
+		}
+		if url.Clicks != 7 {
+			t.Fatalf("clicks = %d, want 7", url.Clicks)
+		}
+	})
+
+	t.Run("analytics", func(t *testing.T) {
+		urls, analytics := newStore(t)
+		id := createTestURL(t, urls, "stats", time.Now())
+		gone := createTestURL(t, urls, "gone", time.Now())
+		if err := urls.Delete(context.Background(), gone); err != nil {
+			t.Fatalf("Delete error = %v", err)
+		}
+
+		base := time.Now().UTC().Truncate(time.Second)
+		n, err := analytics.CreateBatch(context.Background(), []model.AnalyticsEntry{
+			{URLId: id, Timestamp: base, Variant: "a"},
+			{URLId: gone, Timestamp: base},
+			{URLId: id, Timestamp: base.Add(time.Minute), Variant: "b"},
+			{URLId: id, Timestamp: base.Add(2 * time.Minute), Variant: "a"},
+		})
+		if err != nil || n != 4 {
+			t.Fatalf("CreateBatch = %d, %v, want 4, nil", n, err)
+		}
+
+		entries, err := analytics.GetByURLID(context.Background(), id, 10, 0)
+		if err != nil {
+			t.Fatalf("GetByURLID error = %v", err)
+		}
+		if len(entries) != 3 || !entries[0].Timestamp.Equal(base.Add(2*time.Minute)) {
+			t.Fatalf("GetByURLID = %+v, want 3 entries, newest first", entries)
+		}
+		if count, err := analytics.CountByVariant(context.Background(), id, "a"); err != nil || count != 2 {
+			t.Fatalf("CountByVariant(a) = %d, %v, want 2, nil", count, err)
+		}
+		if entries, _ := analytics.GetByURLID(context.Background(), gone, 10, 0); len(entries) != 0 {
+			t.Fatalf("deleted URL has %d entries, want 0", len(entries))
+		}
+
+		if err := analytics.DeleteByURLID(context.Background(), id); err != nil {
+			t.Fatalf("DeleteByURLID error = %v", err)
+		}
+		if entries, _ := analytics.GetByURLID(context.Background(), id, 10, 0); len(entries) != 0 {
+			t.Fatalf("%d entries left after DeleteByURLID, want 0", len(entries))
+		}
+	})
+}
+
+func testURL(code string, createdAt time.Time) model.URL {
+	return model.URL{
+		ShortCode:      code,
+		OriginalURL:    "https://example.com/" + code,
+		CreatedAt:      createdAt,
+		UpdatedAt:      createdAt,
+		Version:        1,
+		RedirectStatus: 301,
+	}
+}
+
+// createTestURL stores a link with code and returns its ID.
+func createTestURL(t *testing.T, urls URLRepository, code string, createdAt time.Time) string {
+	t.Helper()
+
+	id, err := urls.Create(context.Background(), testURL(code, createdAt))
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", code, err)
+	}
+	return id
+}
+
+func shortCodes(urls []model.URL) []string {
+	codes := make([]string, 0, len(urls))
+	for _, url := range urls {
+		codes = append(codes, url.ShortCode)
+	}
+	return codes
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, func(t *testing.T) (URLRepository, AnalyticsRepository) {
+		urls := NewMemoryURLRepository()
+		return urls, NewMemoryAnalyticsRepository(urls)
+	})
+}
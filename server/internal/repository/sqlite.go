@@ -0,0 +1,714 @@
+// Package repository provides SQLite implementation for data persistence.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/config"
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// sqliteTimeLayout is fixed-width so that stored timestamps sort lexically.
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+var sqliteDialect = sqlDialect{
+	name: "sqlite",
+	dir:  "migrations/sqlite",
+	createSQL: `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at TEXT NOT NULL
+	)`,
+	insertSQL:  "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))",
+	appliedSQL: "SELECT version FROM schema_migrations",
+}
+
+// OpenSQLite opens the database file at cfg.SQLitePath in WAL mode.
+func OpenSQLite(cfg *config.Config) (*sql.DB, error) {
+	params := url.Values{}
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "busy_timeout(5000)")
+	params.Add("_pragma", "synchronous(NORMAL)")
+	params.Add("_pragma", "foreign_keys(ON)")
+	params.Set("_txlock", "immediate")
+
+	db, err := sql.Open("sqlite", "file:"+cfg.SQLitePath+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite database %q: %w", cfg.SQLitePath, err)
+	}
+
+	return db, nil
+}
+
+// MigrateSQLite applies pending schema migrations to db.
+func MigrateSQLite(ctx context.Context, db *sql.DB) error {
+	return runMigrations(ctx, db, sqliteDialect)
+}
+
+// SQLiteURLRepository implements URLRepository using SQLite.
+type SQLiteURLRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteURLRepository creates a new SQLite URL repository.
+func NewSQLiteURLRepository(db *sql.DB) *SQLiteURLRepository {
+	return &SQLiteURLRepository{db: db}
+}
+
+// Create inserts a new URL row and returns its ID.
+func (r *SQLiteURLRepository) Create(ctx context.Context, url model.URL) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	docID, err := newID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	rules, err := encodeRules(url.Rules)
+	if err != nil {
+		return "", err
+	}
+	variants, err := encodeVariants(url.Variants)
+	if err != nil {
+		return "", err
+	}
+
+	utm := utmValue(url.UTM)
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO urls (`+urlColumns+`)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		docID, url.ShortCode, url.OriginalURL, sqliteTime(url.CreatedAt), sqliteTime(url.UpdatedAt), url.Clicks, url.UserID,
+		sqliteNullTime(url.ExpiresAt), sqliteNullTime(url.ArchivedAt), url.MaxClicks, url.PasswordHash, url.Version,
+		url.RedirectStatus, url.ForwardQuery, url.ForwardPath,
+		utm.Source, utm.Medium, utm.Campaign, utm.Term, utm.Content, rules, variants,
+		sqliteNullTime(url.ActiveFrom), url.Timezone, url.Disabled, sqliteNullTime(url.DeletedAt),
+	)
+	if err != nil {
+		var sqliteErr *sqlite.Error
+		if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+			return "", ErrDuplicateShortCode
+		}
+		return "", fmt.Errorf("failed to create URL row: %w", err)
+	}
+
+	return docID, nil
+}
+
+// GetByShortCode retrieves a URL by its short code.
+func (r *SQLiteURLRepository) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if shortCode == "" {
+		return nil, fmt.Errorf("short code cannot be empty")
+	}
+
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE short_code = ?`,
+		shortCode,
+	)
+
+	url, err := scanSQLiteURL(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrURLNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query URL by short code: %w", err)
+	}
+
+	return url, nil
+}
+
+// GetAll retrieves paginated URLs and total count.
+func (r *SQLiteURLRepository) GetAll(ctx context.Context, limit, offset int) ([]model.URL, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM urls WHERE deleted_at IS NULL`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count URLs: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE deleted_at IS NULL
+		 ORDER BY created_at DESC, id DESC LIMIT ? OFFSET ?`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list URLs: %w", err)
+	}
+	defer rows.Close()
+
+	urls := make([]model.URL, 0, limit)
+	for rows.Next() {
+		url, err := scanSQLiteURL(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan URL row: %w", err)
+		}
+		urls = append(urls, *url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list URLs: %w", err)
+	}
+
+	return urls, total, nil
+}
+
+// IncrementClicks atomically adds delta to the stored click count for a URL.
+func (r *SQLiteURLRepository) IncrementClicks(ctx context.Context, docID string, delta int) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET clicks = clicks + ?, updated_at = ? WHERE id = ?`,
+		delta, sqliteTime(time.Now()), docID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update URL clicks: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// ConsumeClick increments the click count of a click-limited URL in a
+// single conditional update.
+func (r *SQLiteURLRepository) ConsumeClick(ctx context.Context, docID string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return 0, fmt.Errorf("document ID cannot be empty")
+	}
+
+	var remaining int
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE urls SET clicks = clicks + 1, updated_at = ?
+		 WHERE id = ? AND max_clicks > 0 AND clicks < max_clicks
+		 RETURNING max_clicks - clicks`,
+		sqliteTime(time.Now()), docID,
+	).Scan(&remaining)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, r.consumeFailure(ctx, docID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to consume URL click: %w", err)
+	}
+
+	return remaining, nil
+}
+
+// consumeFailure explains why ConsumeClick matched no row.
+func (r *SQLiteURLRepository) consumeFailure(ctx context.Context, docID string) error {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM urls WHERE id = ?)`, docID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to consume URL click: %w", err)
+	}
+	if !exists {
+		return ErrURLNotFound
+	}
+	return ErrClickLimitReached
+}
+
+// Update stores the mutable fields of url and records change in one
+// transaction.
+func (r *SQLiteURLRepository) Update(ctx context.Context, url model.URL, change model.URLChange) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if url.ID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	changeID, err := newID()
+	if err != nil {
+		return fmt.Errorf("failed to generate ID: %w", err)
+	}
+	rules, err := encodeRules(url.Rules)
+	if err != nil {
+		return err
+	}
+	variants, err := encodeVariants(url.Variants)
+	if err != nil {
+		return err
+	}
+
+	return updateURLWithHistory(ctx, r.db,
+		`UPDATE urls SET original_url = ?, updated_at = ?, expires_at = ?, archived_at = ?,
+		 max_clicks = ?, password_hash = ?, version = ?, redirect_status = ?,
+		 forward_query = ?, forward_path = ?, rules = ?, variants = ?,
+		 active_from = ?, timezone = ?, disabled = ?
+		 WHERE id = ? AND version = ?`,
+		[]any{
+			url.OriginalURL, sqliteTime(url.UpdatedAt), sqliteNullTime(url.ExpiresAt), sqliteNullTime(url.ArchivedAt),
+			url.MaxClicks, url.PasswordHash, url.Version, url.RedirectStatus,
+			url.ForwardQuery, url.ForwardPath, rules, variants,
+			sqliteNullTime(url.ActiveFrom), url.Timezone, url.Disabled, url.ID, url.Version - 1,
+		},
+		`INSERT INTO url_history (`+historyColumns+`)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		[]any{
+			changeID, url.ID, change.Version, change.PreviousURL, change.OriginalURL,
+			joinFields(change.Fields), change.Actor, sqliteTime(change.ChangedAt),
+		},
+		`SELECT EXISTS (SELECT 1 FROM urls WHERE id = ?)`,
+		url.ID,
+	)
+}
+
+// ListHistory returns the recorded changes of a URL, newest first.
+func (r *SQLiteURLRepository) ListHistory(ctx context.Context, docID string) ([]model.URLChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+historyColumns+`
+		 FROM url_history WHERE url_id = ? ORDER BY version DESC`,
+		docID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query URL history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := make([]model.URLChange, 0)
+	for rows.Next() {
+		var (
+			change model.URLChange
+			fields string
+		)
+		if err := rows.Scan(
+			&change.ID,
+			&change.URLId,
+			&change.Version,
+			&change.PreviousURL,
+			&change.OriginalURL,
+			&fields,
+			&change.Actor,
+			(*sqliteTimeScanner)(&change.ChangedAt),
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan URL history row: %w", err)
+		}
+		change.Fields = splitFields(fields)
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query URL history: %w", err)
+	}
+
+	return changes, nil
+}
+
+// Delete removes a URL row by ID.
+func (r *SQLiteURLRepository) Delete(ctx context.Context, docID string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	result, err := r.db.ExecContext(ctx, `DELETE FROM urls WHERE id = ?`, docID)
+	if err != nil {
+		return fmt.Errorf("failed to delete URL row: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// ListExpired returns unarchived URLs that expired at or before before,
+// oldest first.
+func (r *SQLiteURLRepository) ListExpired(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE expires_at <= ? AND archived_at IS NULL AND deleted_at IS NULL
+		 ORDER BY expires_at LIMIT ?`,
+		sqliteTime(before), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired URLs: %w", err)
+	}
+	defer rows.Close()
+
+	urls := make([]model.URL, 0)
+	for rows.Next() {
+		url, err := scanSQLiteURL(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan URL row: %w", err)
+		}
+		urls = append(urls, *url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list expired URLs: %w", err)
+	}
+
+	return urls, nil
+}
+
+// Archive marks a URL row as archived at the given time.
+func (r *SQLiteURLRepository) Archive(ctx context.Context, docID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if docID == "" {
+		return fmt.Errorf("document ID cannot be empty")
+	}
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET archived_at = ?, updated_at = ? WHERE id = ?`,
+		sqliteTime(at), sqliteTime(at), docID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to archive URL row: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// Trash moves a URL to the trash.
+func (r *SQLiteURLRepository) Trash(ctx context.Context, docID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`,
+		sqliteTime(at), sqliteTime(at), docID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to trash URL row: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// Restore takes a URL out of the trash.
+func (r *SQLiteURLRepository) Restore(ctx context.Context, docID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE urls SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`,
+		sqliteTime(at), docID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to restore URL row: %w", err)
+	}
+
+	return requireRowAffected(result)
+}
+
+// ListTrash returns paginated trashed URLs, most recently deleted first, and
+// their total count.
+func (r *SQLiteURLRepository) ListTrash(ctx context.Context, limit, offset int) ([]model.URL, int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT count(*) FROM urls WHERE deleted_at IS NOT NULL`).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count trashed URLs: %w", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE deleted_at IS NOT NULL
+		 ORDER BY deleted_at DESC, id DESC LIMIT ? OFFSET ?`,
+		limit, offset,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list trashed URLs: %w", err)
+	}
+
+	urls, err := collectURLs(rows, scanSQLiteURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list trashed URLs: %w", err)
+	}
+	return urls, total, nil
+}
+
+// ListTrashedBefore returns URLs trashed at or before before, oldest first.
+func (r *SQLiteURLRepository) ListTrashedBefore(ctx context.Context, before time.Time, limit int) ([]model.URL, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+urlColumns+`
+		 FROM urls WHERE deleted_at <= ?
+		 ORDER BY deleted_at LIMIT ?`,
+		sqliteTime(before), limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed URLs: %w", err)
+	}
+
+	urls, err := collectURLs(rows, scanSQLiteURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trashed URLs: %w", err)
+	}
+	return urls, nil
+}
+
+// SQLiteAnalyticsRepository implements AnalyticsRepository using SQLite.
+type SQLiteAnalyticsRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteAnalyticsRepository creates a new SQLite analytics repository.
+func NewSQLiteAnalyticsRepository(db *sql.DB) *SQLiteAnalyticsRepository {
+	return &SQLiteAnalyticsRepository{db: db}
+}
+
+// Create inserts a new analytics entry and returns its ID.
+func (r *SQLiteAnalyticsRepository) Create(ctx context.Context, entry model.AnalyticsEntry) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if entry.URLId == "" {
+		return "", fmt.Errorf("URL ID cannot be empty for analytics entry")
+	}
+
+	entryID, err := newID()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO analytics (`+analyticsColumns+`)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entryID, entry.URLId, sqliteTime(entry.Timestamp), entry.UserAgent, entry.IPAddress, entry.Referer, entry.Campaign, entry.Variant,
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create analytics entry: %w", err)
+	}
+
+	return entryID, nil
+}
+
+// CreateBatch inserts all entries whose URL still exists in a single
+// transaction.
+func (r *SQLiteAnalyticsRepository) CreateBatch(ctx context.Context, entries []model.AnalyticsEntry) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	err := insertAnalyticsBatch(ctx, r.db, entries,
+		`INSERT INTO analytics (`+analyticsColumns+`)
+		 SELECT ?, ?, ?, ?, ?, ?, ?, ?
+		 WHERE EXISTS (SELECT 1 FROM urls WHERE id = ?)`,
+		func(entryID string, entry model.AnalyticsEntry) []any {
+			return []any{entryID, entry.URLId, sqliteTime(entry.Timestamp), entry.UserAgent, entry.IPAddress, entry.Referer, entry.Campaign, entry.Variant, entry.URLId}
+		},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// GetByURLID retrieves analytics entries for a URL with pagination.
+func (r *SQLiteAnalyticsRepository) GetByURLID(ctx context.Context, urlID string, limit, offset int) ([]model.AnalyticsEntry, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if urlID == "" {
+		return nil, fmt.Errorf("URL ID cannot be empty")
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+analyticsColumns+`
+		 FROM analytics WHERE url_id = ?
+		 ORDER BY timestamp DESC LIMIT ? OFFSET ?`,
+		urlID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analytics: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]model.AnalyticsEntry, 0)
+	for rows.Next() {
+		var entry model.AnalyticsEntry
+		if err := rows.Scan(
+			&entry.ID,
+			&entry.URLId,
+			(*sqliteTimeScanner)(&entry.Timestamp),
+			&entry.UserAgent,
+			&entry.IPAddress,
+			&entry.Referer,
+			&entry.Campaign,
+			&entry.Variant,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan analytics row: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query analytics: %w", err)
+	}
+
+	return entries, nil
+}
+
+// CountByVariant counts the analytics entries of a URL served variant.
+func (r *SQLiteAnalyticsRepository) CountByVariant(ctx context.Context, urlID, variant string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM analytics WHERE url_id = ? AND variant = ?`,
+		urlID, variant,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count analytics: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteByURLID removes all analytics entries of a URL.
+func (r *SQLiteAnalyticsRepository) DeleteByURLID(ctx context.Context, urlID string) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM analytics WHERE url_id = ?`, urlID); err != nil {
+		return fmt.Errorf("failed to delete analytics: %w", err)
+	}
+	return nil
+}
+
+func scanSQLiteURL(row rowScanner) (*model.URL, error) {
+	var (
+		url             model.URL
+		utm             model.UTM
+		rules, variants string
+	)
+	if err := row.Scan(
+		&url.ID,
+		&url.ShortCode,
+		&url.OriginalURL,
+		(*sqliteTimeScanner)(&url.CreatedAt),
+		(*sqliteTimeScanner)(&url.UpdatedAt),
+		&url.Clicks,
+		&url.UserID,
+		sqliteOptionalTime{&url.ExpiresAt},
+		sqliteOptionalTime{&url.ArchivedAt},
+		&url.MaxClicks,
+		&url.PasswordHash,
+		&url.Version,
+		&url.RedirectStatus,
+		&url.ForwardQuery,
+		&url.ForwardPath,
+		&utm.Source,
+		&utm.Medium,
+		&utm.Campaign,
+		&utm.Term,
+		&utm.Content,
+		&rules,
+		&variants,
+		sqliteOptionalTime{&url.ActiveFrom},
+		&url.Timezone,
+		&url.Disabled,
+		sqliteOptionalTime{&url.DeletedAt},
+	); err != nil {
+		return nil, err
+	}
+
+	decodedRules, err := decodeRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	decodedVariants, err := decodeVariants(variants)
+	if err != nil {
+		return nil, err
+	}
+	url.UTM = utmPtr(utm)
+	url.Rules = decodedRules
+	url.Variants = decodedVariants
+	return &url, nil
+}
+
+// sqliteTime formats t in UTC using sqliteTimeLayout.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeLayout)
+}
+
+// sqliteNullTime formats an optional time, mapping nil to NULL.
+func sqliteNullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return sqliteTime(*t)
+}
+
+// sqliteOptionalTime scans a nullable timestamp column into *time.Time.
+type sqliteOptionalTime struct {
+	dst **time.Time
+}
+
+// Scan implements sql.Scanner.
+func (s sqliteOptionalTime) Scan(src any) error {
+	if src == nil {
+		*s.dst = nil
+		return nil
+	}
+
+	var t time.Time
+	if err := (*sqliteTimeScanner)(&t).Scan(src); err != nil {
+		return err
+	}
+	*s.dst = &t
+	return nil
+}
+
+// sqliteTimeScanner parses timestamps written by sqliteTime.
+type sqliteTimeScanner time.Time
+
+// Scan implements sql.Scanner.
+func (s *sqliteTimeScanner) Scan(src any) error {
+	var raw string
+	switch v := src.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	case time.Time:
+		*s = sqliteTimeScanner(v.UTC())
+		return nil
+	case nil:
+		*s = sqliteTimeScanner(time.Time{})
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into timestamp", src)
+	}
+
+	parsed, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp %q: %w", raw, err)
+	}
+	*s = sqliteTimeScanner(parsed.UTC())
+	return nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/abhisheksharm-3/shrtn/internal/config"
+)
+
+// openTestSQLite opens a new database file in a temporary directory.
+func openTestSQLite(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := OpenSQLite(&config.Config{SQLitePath: filepath.Join(t.TempDir(), "shrtn.db")})
+	if err != nil {
+		t.Fatalf("OpenSQLite error = %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestMigrateSQLite(t *testing.T) {
+	db := openTestSQLite(t)
+	migrations, err := loadMigrations(sqliteDialect.dir)
+	if err != nil {
+		t.Fatalf("loadMigrations error = %v", err)
+	}
+
+	for run := 1; run <= 2; run++ {
+		if err := MigrateSQLite(context.Background(), db); err != nil {
+			t.Fatalf("run %d: MigrateSQLite error = %v", run, err)
+		}
+		var applied int
+		if err := db.QueryRow("SELECT count(*) FROM schema_migrations").Scan(&applied); err != nil {
+			t.Fatalf("failed to count migrations: %v", err)
+		}
+		if applied != len(migrations) {
+			t.Fatalf("run %d: %d migrations recorded, want %d", run, applied, len(migrations))
+		}
+	}
+
+	for _, index := range []string{"urls_short_code_key", "analytics_url_id_timestamp_idx"} {
+		var name string
+		err := db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'index' AND name = ?", index).Scan(&name)
+		if err != nil {
+			t.Errorf("index %s: %v", index, err)
+		}
+	}
+}
+
+func TestOpenSQLiteUsesWAL(t *testing.T) {
+	db := openTestSQLite(t)
+
+	var mode string
+	if err := db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil {
+		t.Fatalf("failed to read journal mode: %v", err)
+	}
+	if mode != "wal" {
+		t.Fatalf("journal mode = %q, want wal", mode)
+	}
+}
+
+func TestSQLiteStore(t *testing.T) {
+	testStore(t, func(t *testing.T) (URLRepository, AnalyticsRepository) {
+		db := openTestSQLite(t)
+		if err := MigrateSQLite(context.Background(), db); err != nil {
+			t.Fatalf("MigrateSQLite error = %v", err)
+		}
+		return NewSQLiteURLRepository(db), NewSQLiteAnalyticsRepository(db)
+	})
+}
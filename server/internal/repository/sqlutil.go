@@ -0,0 +1,237 @@
+// Package repository provides helpers shared by the SQL backends.
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+// urlColumns lists the urls table columns in the order scanned by scanURL
+// and scanSQLiteURL.
+const urlColumns = "id, short_code, original_url, created_at, updated_at, clicks, user_id, " +
+	"expires_at, archived_at, max_clicks, password_hash, version, redirect_status, " +
+	"forward_query, forward_path, utm_source, utm_medium, utm_campaign, utm_term, utm_content, rules, variants, " +
+	"active_from, timezone, disabled, deleted_at"
+
+// analyticsColumns lists the analytics table columns in scan order.
+const analyticsColumns = "id, url_id, timestamp, user_agent, ip_address, referer, campaign, variant"
+
+// historyColumns lists the url_history table columns in scan order.
+const historyColumns = "id, url_id, version, previous_url, original_url, changed_fields, actor, changed_at"
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// insertAnalyticsBatch runs insertSQL once per entry inside one transaction
+// using a prepared statement; args maps an entry to the statement arguments.
+func insertAnalyticsBatch(
+	ctx context.Context,
+	db *sql.DB,
+	entries []model.AnalyticsEntry,
+	insertSQL string,
+	args func(entryID string, entry model.AnalyticsEntry) []any,
+) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin analytics batch: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, insertSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare analytics batch: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		if entry.URLId == "" {
+			return fmt.Errorf("URL ID cannot be empty for analytics entry")
+		}
+		entryID, err := newID()
+		if err != nil {
+			return fmt.Errorf("failed to generate ID: %w", err)
+		}
+		if _, err := stmt.ExecContext(ctx, args(entryID, entry)...); err != nil {
+			return fmt.Errorf("failed to insert analytics batch: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit analytics batch: %w", err)
+	}
+	return nil
+}
+
+// updateURLWithHistory runs updateSQL and, when it matched a row, historySQL
+// in one transaction. When updateSQL matches nothing, existsSQL decides
+// between ErrURLNotFound and ErrVersionConflict.
+func updateURLWithHistory(
+	ctx context.Context,
+	db *sql.DB,
+	updateSQL string,
+	updateArgs []any,
+	historySQL string,
+	historyArgs []any,
+	existsSQL string,
+	docID string,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin URL update: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, updateSQL, updateArgs...)
+	if err != nil {
+		return fmt.Errorf("failed to update URL row: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+
+	if affected == 0 {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, existsSQL, docID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to update URL row: %w", err)
+		}
+		if !exists {
+			return ErrURLNotFound
+		}
+		return ErrVersionConflict
+	}
+
+	if _, err := tx.ExecContext(ctx, historySQL, historyArgs...); err != nil {
+		return fmt.Errorf("failed to record URL history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit URL update: %w", err)
+	}
+	return nil
+}
+
+// collectURLs scans and closes rows.
+func collectURLs(rows *sql.Rows, scan func(rowScanner) (*model.URL, error)) ([]model.URL, error) {
+	defer rows.Close()
+
+	urls := make([]model.URL, 0)
+	for rows.Next() {
+		url, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		urls = append(urls, *url)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
+
+// joinFields and splitFields encode URLChange.Fields in a text column.
+func joinFields(fields []string) string {
+	return strings.Join(fields, ",")
+}
+
+func splitFields(value string) []string {
+	if value == "" {
+		return []string{}
+	}
+	return strings.Split(value, ",")
+}
+
+func requireRowAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrURLNotFound
+	}
+	return nil
+}
+
+// utmValue and utmPtr convert between URL.UTM and the utm_* columns, which
+// are empty strings when unset.
+func utmValue(utm *model.UTM) model.UTM {
+	if utm == nil {
+		return model.UTM{}
+	}
+	return *utm
+}
+
+func utmPtr(utm model.UTM) *model.UTM {
+	if utm.IsZero() {
+		return nil
+	}
+	return &utm
+}
+
+// encodeRules and decodeRules store URL.Rules as JSON in a text column,
+// which is empty for links without rules.
+func encodeRules(rules []model.RedirectRule) (string, error) {
+	if len(rules) == 0 {
+		return "", nil
+	}
+	encoded, err := json.Marshal(rules)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode rules: %w", err)
+	}
+	return string(encoded), nil
+}
+
+func decodeRules(value string) ([]model.RedirectRule, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var rules []model.RedirectRule
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode rules: %w", err)
+	}
+	return rules, nil
+}
+
+// encodeVariants and decodeVariants store URL.Variants as JSON in a text
+// column, which is empty for links without variants.
+func encodeVariants(variants []model.Variant) (string, error) {
+	if len(variants) == 0 {
+		return "", nil
+	}
+	encoded, err := json.Marshal(variants)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode variants: %w", err)
+	}
+	return string(encoded), nil
+}
+
+func decodeVariants(value string) ([]model.Variant, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var variants []model.Variant
+	if err := json.Unmarshal([]byte(value), &variants); err != nil {
+		return nil, fmt.Errorf("failed to decode variants: %w", err)
+	}
+	return variants, nil
+}
+
+// nullTimePtr converts a nullable timestamp column to an optional UTC time.
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	utc := t.Time.UTC()
+	return &utc
+}
@@ -3,47 +3,261 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/abhisheksharm-3/shrtn/internal/model"
 	"github.com/abhisheksharm-3/shrtn/internal/repository"
 )
 
-// AnalyticsService handles URL click analytics.
+// Queue policies applied when the analytics queue is full.
+const (
+	QueuePolicyDrop  = "drop"
+	QueuePolicyBlock = "block"
+)
+
+var (
+	ErrAnalyticsQueueFull = errors.New("analytics queue is full")
+	ErrAnalyticsClosed    = errors.New("analytics pipeline is closed")
+)
+
+// AnalyticsConfig configures the analytics pipeline.
+type AnalyticsConfig struct {
+	TrustedProxy  string
+	QueueSize     int
+	Workers       int
+	BatchSize     int
+	FlushInterval time.Duration
+	QueuePolicy   string
+}
+
+// AnalyticsStats reports the state of the analytics pipeline.
+type AnalyticsStats struct {
+	Queued   int   `json:"queued"`
+	Enqueued int64 `json:"enqueued"`
+	Dropped  int64 `json:"dropped"`
+	Written  int64 `json:"written"`
+	Failed   int64 `json:"failed"`
+
+	Spool *SpoolStats `json:"spool,omitempty"`
+}
+
+// AnalyticsService handles URL click analytics. Click events are queued on a
+// bounded channel and written in batches by a fixed pool of workers. Events
+// that fail to persist are handed to the spool when one is configured.
 type AnalyticsService struct {
-	repo         repository.AnalyticsRepository
-	trustedProxy string
+	repo   repository.AnalyticsRepository
+	spool  *AnalyticsSpool
+	config AnalyticsConfig
+
+	mu     sync.RWMutex
+	closed bool
+	queue  chan model.AnalyticsEntry
+	wg     sync.WaitGroup
+
+	enqueued atomic.Int64
+	dropped  atomic.Int64
+	written  atomic.Int64
+	failed   atomic.Int64
 }
 
-// NewAnalyticsService creates a new AnalyticsService with the given repository.
-func NewAnalyticsService(repo repository.AnalyticsRepository, trustedProxy string) *AnalyticsService {
-	return &AnalyticsService{
-		repo:         repo,
-		trustedProxy: trustedProxy,
+// NewAnalyticsService creates a new AnalyticsService and starts its workers.
+// spool may be nil, in which case failed writes are discarded.
+func NewAnalyticsService(repo repository.AnalyticsRepository, spool *AnalyticsSpool, cfg AnalyticsConfig) *AnalyticsService {
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 2
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 2 * time.Second
+	}
+	if cfg.QueuePolicy == "" {
+		cfg.QueuePolicy = QueuePolicyDrop
+	}
+
+	s := &AnalyticsService{
+		repo:   repo,
+		spool:  spool,
+		config: cfg,
+		queue:  make(chan model.AnalyticsEntry, cfg.QueueSize),
+	}
+
+	s.wg.Add(cfg.Workers)
+	for i := 0; i < cfg.Workers; i++ {
+		go s.worker()
 	}
+
+	return s
 }
 
-// RecordClick records a click event for a URL.
-func (s *AnalyticsService) RecordClick(ctx context.Context, urlID string, req *http.Request) error {
+// RecordClick queues a click event for url, tagged with its UTM campaign
+// and the A/B variant served, if any. With the drop policy a full queue
+// discards the event; with the block policy the call waits for space until
+// ctx is done.
+func (s *AnalyticsService) RecordClick(ctx context.Context, url *model.URL, variant string, req *http.Request) error {
 	entry := model.AnalyticsEntry{
-		URLId:     urlID,
+		URLId:     url.ID,
 		Timestamp: time.Now().UTC(),
 		UserAgent: req.UserAgent(),
-		IPAddress: s.extractClientIP(req),
+		IPAddress: s.ClientIP(req),
 		Referer:   req.Referer(),
+		Variant:   variant,
+	}
+	if url.UTM != nil {
+		entry.Campaign = url.UTM.Campaign
 	}
 
-	_, err := s.repo.Create(ctx, entry)
-	return err
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		s.dropped.Add(1)
+		return ErrAnalyticsClosed
+	}
+
+	if s.config.QueuePolicy == QueuePolicyBlock {
+		select {
+		case s.queue <- entry:
+			s.enqueued.Add(1)
+			return nil
+		case <-ctx.Done():
+			s.dropped.Add(1)
+			return ctx.Err()
+		}
+	}
+
+	select {
+	case s.queue <- entry:
+		s.enqueued.Add(1)
+		return nil
+	default:
+		s.dropped.Add(1)
+		return ErrAnalyticsQueueFull
+	}
+}
+
+// VariantStats returns the recorded clicks of each A/B variant of url.
+// Clicks still queued or spooled are not yet counted.
+func (s *AnalyticsService) VariantStats(ctx context.Context, url *model.URL) ([]model.VariantStats, error) {
+	stats := make([]model.VariantStats, 0, len(url.Variants))
+	for _, variant := range url.Variants {
+		clicks, err := s.repo.CountByVariant(ctx, url.ID, variant.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count variant %s: %w", variant.ID, err)
+		}
+		stats = append(stats, model.VariantStats{Variant: variant, Clicks: clicks})
+	}
+	return stats, nil
+}
+
+// Close stops accepting events and waits for queued events to be written or
+// for ctx to expire.
+func (s *AnalyticsService) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns pipeline counters.
+func (s *AnalyticsService) Stats() AnalyticsStats {
+	stats := AnalyticsStats{
+		Queued:   len(s.queue),
+		Enqueued: s.enqueued.Load(),
+		Dropped:  s.dropped.Load(),
+		Written:  s.written.Load(),
+		Failed:   s.failed.Load(),
+	}
+	if s.spool != nil {
+		spoolStats := s.spool.Stats()
+		stats.Spool = &spoolStats
+	}
+	return stats
+}
+
+func (s *AnalyticsService) worker() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]model.AnalyticsEntry, 0, s.config.BatchSize)
+	for {
+		select {
+		case entry, ok := <-s.queue:
+			if !ok {
+				s.writeBatch(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= s.config.BatchSize {
+				s.writeBatch(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.writeBatch(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (s *AnalyticsService) writeBatch(batch []model.AnalyticsEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	written, err := s.repo.CreateBatch(context.Background(), batch)
+	s.written.Add(int64(written))
+	if err == nil {
+		return
+	}
+
+	remaining := batch[written:]
+	s.failed.Add(int64(len(remaining)))
+	if s.spool == nil {
+		log.Printf("analytics: failed to write %d events: %v", len(remaining), err)
+		return
+	}
+
+	if spoolErr := s.spool.Append(remaining); spoolErr != nil {
+		log.Printf("analytics: failed to write %d events: %v; spool: %v", len(remaining), err, spoolErr)
+	}
 }
 
-func (s *AnalyticsService) extractClientIP(r *http.Request) string {
-	if s.trustedProxy != "" {
+// ClientIP returns the IP address of the client that sent r. Forwarding
+// headers are only trusted on requests from the configured trusted proxy or
+// the loopback address.
+func (s *AnalyticsService) ClientIP(r *http.Request) string {
+	if s.config.TrustedProxy != "" {
 		remoteIP, _, _ := net.SplitHostPort(r.RemoteAddr)
-		if remoteIP == s.trustedProxy || remoteIP == "127.0.0.1" {
+		if remoteIP == s.config.TrustedProxy || remoteIP == "127.0.0.1" {
 			if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 				parts := strings.Split(xff, ",")
 				if len(parts) > 0 {
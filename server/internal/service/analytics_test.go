@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+)
+
+// stalledRepo holds every CreateBatch call until release is closed, signalling
+// on started as each one begins, so the pipeline's queue can be filled.
+type stalledRepo struct {
+	repository.AnalyticsRepository
+
+	started chan struct{}
+	release chan struct{}
+}
+
+func newStalledRepo() *stalledRepo {
+	return &stalledRepo{
+		started: make(chan struct{}, 100),
+		release: make(chan struct{}),
+	}
+}
+
+func (r *stalledRepo) CreateBatch(_ context.Context, entries []model.AnalyticsEntry) (int, error) {
+	r.started <- struct{}{}
+	<-r.release
+	return len(entries), nil
+}
+
+// fillQueue starts a pipeline with one worker and a one-slot queue, and
+// records clicks until the worker is stalled on a write and the queue is
+// full.
+func fillQueue(t *testing.T, repo *stalledRepo, policy string) *AnalyticsService {
+	t.Helper()
+
+	service := NewAnalyticsService(repo, nil, AnalyticsConfig{
+		QueueSize:   1,
+		Workers:     1,
+		BatchSize:   1,
+		QueuePolicy: policy,
+	})
+	url := &model.URL{ID: "url"}
+	req := httptest.NewRequest("GET", "/", nil)
+
+	if err := service.RecordClick(context.Background(), url, "", req); err != nil {
+		t.Fatalf("first click error = %v", err)
+	}
+	<-repo.started
+	if err := service.RecordClick(context.Background(), url, "", req); err != nil {
+		t.Fatalf("second click error = %v", err)
+	}
+	return service
+}
+
+func TestRecordClickDropsWhenQueueIsFull(t *testing.T) {
+	repo := newStalledRepo()
+	service := fillQueue(t, repo, QueuePolicyDrop)
+
+	start := time.Now()
+	err := service.RecordClick(context.Background(), &model.URL{ID: "url"}, "", httptest.NewRequest("GET", "/", nil))
+	if !errors.Is(err, ErrAnalyticsQueueFull) {
+		t.Fatalf("RecordClick error = %v, want ErrAnalyticsQueueFull", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("RecordClick took %v with the drop policy", elapsed)
+	}
+
+	close(repo.release)
+	if err := service.Close(context.Background()); err != nil {
+		t.Fatalf("Close error = %v", err)
+	}
+	if stats := service.Stats(); stats.Dropped != 1 || stats.Written != 2 {
+		t.Fatalf("stats = %+v, want 1 dropped and 2 written", stats)
+	}
+}
+
+func TestRecordClickBlocksWhenQueueIsFull(t *testing.T) {
+	repo := newStalledRepo()
+	service := fillQueue(t, repo, QueuePolicyBlock)
+	url := &model.URL{ID: "url"}
+	req := httptest.NewRequest("GET", "/", nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := service.RecordClick(ctx, url, "", req); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RecordClick error = %v, want context.DeadlineExceeded", err)
+	}
+
+	recorded := make(chan error, 1)
+	go func() { recorded <- service.RecordClick(context.Background(), url, "", req) }()
+	select {
+	case err := <-recorded:
+		t.Fatalf("RecordClick returned %v while the queue was full", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(repo.release)
+	if err := <-recorded; err != nil {
+		t.Fatalf("RecordClick error = %v once the queue drained", err)
+	}
+	if err := service.Close(context.Background()); err != nil {
+		t.Fatalf("Close error = %v", err)
+	}
+	if stats := service.Stats(); stats.Dropped != 1 || stats.Written != 3 {
+		t.Fatalf("stats = %+v, want 1 dropped and 3 written", stats)
+	}
+}
+
+func TestRecordClickAfterClose(t *testing.T) {
+	service := NewAnalyticsService(newStalledRepo(), nil, AnalyticsConfig{})
+	if err := service.Close(context.Background()); err != nil {
+		t.Fatalf("Close error = %v", err)
+	}
+
+	err := service.RecordClick(context.Background(), &model.URL{ID: "url"}, "", httptest.NewRequest("GET", "/", nil))
+	if !errors.Is(err, ErrAnalyticsClosed) {
+		t.Fatalf("RecordClick error = %v, want ErrAnalyticsClosed", err)
+	}
+}
@@ -0,0 +1,138 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+type attemptRecord struct {
+	failures    int
+	windowStart time.Time
+}
+
+// AttemptLimiter counts attempts per key within a fixed window and rejects
+// further attempts once the limit is reached, until the window ends. Attempts
+// that succeed are forgotten with Reset.
+type AttemptLimiter struct {
+	maxFailures int
+	window      time.Duration
+
+	mu        sync.Mutex
+	records   map[string]*attemptRecord
+	lastPrune time.Time
+}
+
+// NewAttemptLimiter creates an AttemptLimiter that allows maxFailures failed
+// attempts per key every window.
+func NewAttemptLimiter(maxFailures int, window time.Duration) *AttemptLimiter {
+	if maxFailures <= 0 {
+		maxFailures = 5
+	}
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+
+	return &AttemptLimiter{
+		maxFailures: maxFailures,
+		window:      window,
+		records:     make(map[string]*attemptRecord),
+	}
+}
+
+// Allow reserves an attempt for key and reports whether it is permitted.
+// Checking and counting happen together, so concurrent attempts cannot get
+// past the limit. A reserved attempt counts as failed until Reset.
+func (l *AttemptLimiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	record, ok := l.records[key]
+	if !ok || now.Sub(record.windowStart) >= l.window {
+		l.records[key] = &attemptRecord{failures: 1, windowStart: now}
+		l.pruneLocked(now)
+		return true
+	}
+	if record.failures >= l.maxFailures {
+		return false
+	}
+	record.failures++
+	return true
+}
+
+// Reset forgets the attempts for key, including the one reserved by the
+// last Allow.
+func (l *AttemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	delete(l.records, key)
+	l.mu.Unlock()
+}
+
+// Release gives back the attempt reserved for key by the last Allow,
+// leaving earlier attempts counted.
+func (l *AttemptLimiter) Release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if record, ok := l.records[key]; ok && record.failures > 0 {
+		record.failures--
+	}
+}
+
+// pruneLocked drops records whose window has ended, at most once per window.
+func (l *AttemptLimiter) pruneLocked(now time.Time) {
+	if now.Sub(l.lastPrune) < l.window {
+		return
+	}
+	l.lastPrune = now
+
+	for key, record := range l.records {
+		if now.Sub(record.windowStart) >= l.window {
+			delete(l.records, key)
+		}
+	}
+}
+
+// PasswordLimiter limits attempts at the password of a link, both per link
+// and client address and per link across all clients, so guesses spread
+// over many addresses still run into a limit.
+type PasswordLimiter struct {
+	clients *AttemptLimiter
+	links   *AttemptLimiter
+}
+
+// NewPasswordLimiter creates a PasswordLimiter that allows perClient failed
+// attempts per link and client address, and perLink failed attempts per
+// link, every window.
+func NewPasswordLimiter(perClient, perLink int, window time.Duration) *PasswordLimiter {
+	if perLink <= 0 {
+		perLink = 100
+	}
+	return &PasswordLimiter{
+		clients: NewAttemptLimiter(perClient, window),
+		links:   NewAttemptLimiter(perLink, window),
+	}
+}
+
+// Allow reserves an attempt at the password of shortCode from clientIP and
+// reports whether it is permitted.
+func (p *PasswordLimiter) Allow(shortCode, clientIP string) bool {
+	clientKey := shortCode + "|" + clientIP
+	if !p.clients.Allow(clientKey) {
+		return false
+	}
+	if !p.links.Allow(shortCode) {
+		p.clients.Release(clientKey)
+		return false
+	}
+	return true
+}
+
+// Succeed records that the attempt reserved by Allow was correct: the
+// client's attempts are forgotten and the link's count no longer includes
+// this attempt.
+func (p *PasswordLimiter) Succeed(shortCode, clientIP string) {
+	p.clients.Reset(shortCode + "|" + clientIP)
+	p.links.Release(shortCode)
+}
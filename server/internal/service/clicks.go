@@ -0,0 +1,127 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+)
+
+// ClickCounterStats reports the state of a ClickCounter.
+type ClickCounterStats struct {
+	Pending int64 `json:"pending"`
+	Flushed int64 `json:"flushed"`
+	Failed  int64 `json:"failedFlushes"`
+}
+
+// ClickCounter aggregates click increments per URL ID in memory and flushes
+// them to the repository as atomic deltas, so concurrent redirects never
+// overwrite each other's counts.
+type ClickCounter struct {
+	repo     repository.URLRepository
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]int
+
+	queued  atomic.Int64
+	flushed atomic.Int64
+	failed  atomic.Int64
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewClickCounter creates a ClickCounter that flushes every interval.
+func NewClickCounter(repo repository.URLRepository, interval time.Duration) *ClickCounter {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	c := &ClickCounter{
+		repo:     repo,
+		interval: interval,
+		pending:  make(map[string]int),
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go c.run()
+	return c
+}
+
+// Add records one click for urlID.
+func (c *ClickCounter) Add(urlID string) {
+	c.mu.Lock()
+	c.pending[urlID]++
+	c.mu.Unlock()
+	c.queued.Add(1)
+}
+
+// Flush writes all pending increments to the repository. Deltas that fail to
+// persist are kept for the next flush unless the URL no longer exists.
+func (c *ClickCounter) Flush(ctx context.Context) error {
+	c.mu.Lock()
+	batch := c.pending
+	c.pending = make(map[string]int, len(batch))
+	c.mu.Unlock()
+
+	var errs []error
+	for urlID, delta := range batch {
+		err := c.repo.IncrementClicks(ctx, urlID, delta)
+		switch {
+		case err == nil:
+			c.queued.Add(-int64(delta))
+			c.flushed.Add(int64(delta))
+		case errors.Is(err, repository.ErrURLNotFound):
+			c.queued.Add(-int64(delta))
+		default:
+			c.failed.Add(1)
+			errs = append(errs, err)
+			c.mu.Lock()
+			c.pending[urlID] += delta
+			c.mu.Unlock()
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Stop halts the periodic flush and writes any remaining increments.
+func (c *ClickCounter) Stop(ctx context.Context) error {
+	c.stopOnce.Do(func() { close(c.stopChan) })
+	<-c.done
+	return c.Flush(ctx)
+}
+
+// Stats returns current pending and flushed click counts.
+func (c *ClickCounter) Stats() ClickCounterStats {
+	return ClickCounterStats{
+		Pending: c.queued.Load(),
+		Flushed: c.flushed.Load(),
+		Failed:  c.failed.Load(),
+	}
+}
+
+func (c *ClickCounter) run() {
+	defer close(c.done)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.Flush(context.Background()); err != nil {
+				log.Printf("click counter: flush failed: %v", err)
+			}
+		case <-c.stopChan:
+			return
+		}
+	}
+}
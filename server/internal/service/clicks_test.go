@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+)
+
+// deltaRepo records the deltas IncrementClicks receives and fails them while
+// err is set.
+type deltaRepo struct {
+	repository.URLRepository
+
+	mu     sync.Mutex
+	err    error
+	deltas map[string][]int
+}
+
+func (r *deltaRepo) IncrementClicks(_ context.Context, docID string, delta int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return r.err
+	}
+	if r.deltas == nil {
+		r.deltas = make(map[string][]int)
+	}
+	r.deltas[docID] = append(r.deltas[docID], delta)
+	return nil
+}
+
+func (r *deltaRepo) setErr(err error) {
+	r.mu.Lock()
+	r.err = err
+	r.mu.Unlock()
+}
+
+func TestClickCounterFlushesDeltas(t *testing.T) {
+	repo := &deltaRepo{}
+	counter := NewClickCounter(repo, time.Hour)
+	defer counter.Stop(context.Background())
+
+	for range 3 {
+		counter.Add("a")
+	}
+	counter.Add("b")
+	if pending := counter.Stats().Pending; pending != 4 {
+		t.Fatalf("pending = %d, want 4", pending)
+	}
+
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush error = %v", err)
+	}
+	if got := repo.deltas["a"]; len(got) != 1 || got[0] != 3 {
+		t.Errorf("deltas for a = %v, want [3]", got)
+	}
+	if got := repo.deltas["b"]; len(got) != 1 || got[0] != 1 {
+		t.Errorf("deltas for b = %v, want [1]", got)
+	}
+	if stats := counter.Stats(); stats.Pending != 0 || stats.Flushed != 4 {
+		t.Errorf("stats = %+v, want 0 pending and 4 flushed", stats)
+	}
+
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("empty Flush error = %v", err)
+	}
+	if got := repo.deltas["a"]; len(got) != 1 {
+		t.Errorf("empty flush wrote deltas %v", got)
+	}
+}
+
+func TestClickCounterRetriesFailedFlush(t *testing.T) {
+	repo := &deltaRepo{err: errors.New("database unavailable")}
+	counter := NewClickCounter(repo, time.Hour)
+	defer counter.Stop(context.Background())
+
+	counter.Add("a")
+	counter.Add("a")
+	if err := counter.Flush(context.Background()); err == nil {
+		t.Fatal("Flush succeeded while the repository was failing")
+	}
+	if stats := counter.Stats(); stats.Pending != 2 || stats.Failed != 1 {
+		t.Fatalf("stats after failure = %+v, want 2 pending and 1 failed flush", stats)
+	}
+
+	counter.Add("a")
+	repo.setErr(nil)
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush error = %v", err)
+	}
+	if got := repo.deltas["a"]; len(got) != 1 || got[0] != 3 {
+		t.Fatalf("deltas for a = %v, want the failed and new clicks as [3]", got)
+	}
+	if stats := counter.Stats(); stats.Pending != 0 || stats.Flushed != 3 {
+		t.Fatalf("stats = %+v, want 0 pending and 3 flushed", stats)
+	}
+}
+
+func TestClickCounterDropsDeletedURLs(t *testing.T) {
+	repo := &deltaRepo{err: repository.ErrURLNotFound}
+	counter := NewClickCounter(repo, time.Hour)
+	defer counter.Stop(context.Background())
+
+	counter.Add("gone")
+	if err := counter.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush error = %v", err)
+	}
+	if stats := counter.Stats(); stats.Pending != 0 || stats.Failed != 0 {
+		t.Fatalf("stats = %+v, want the click dropped without a failure", stats)
+	}
+}
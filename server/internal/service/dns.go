@@ -0,0 +1,72 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// DNS checks applied to link destinations.
+const (
+	// DNSCheckOff only checks hosts that are literal IP addresses.
+	DNSCheckOff = "off"
+	// DNSCheckBlock also rejects hostnames that resolve to a blocked
+	// address. Hostnames that do not resolve are accepted.
+	DNSCheckBlock = "block"
+	// DNSCheckStrict also rejects hostnames that do not resolve.
+	DNSCheckStrict = "strict"
+)
+
+// ErrUnresolvableHost is returned by the strict DNS check for destination
+// hosts that do not resolve.
+var ErrUnresolvableHost = errors.New("destination host could not be resolved")
+
+// Resolver looks up the addresses of a host. *net.Resolver implements it.
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// checkHost rejects destination hosts that are, or with DNS checks enabled
+// resolve to, an address outbound requests may not reach. A hostname is
+// rejected when any of its addresses is blocked, since a client may use any
+// of them.
+func (s *URLService) checkHost(ctx context.Context, host string) error {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if IsBlockedIP(ip) {
+			return ErrURLBlocked
+		}
+		return nil
+	}
+	if s.config.DNSCheck == "" || s.config.DNSCheck == DNSCheckOff {
+		return nil
+	}
+
+	resolver := s.config.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	timeout := s.config.DNSTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	addrs, err := resolver.LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		if s.config.DNSCheck == DNSCheckStrict {
+			return ErrUnresolvableHost
+		}
+		return nil
+	}
+	for _, addr := range addrs {
+		if IsBlockedIP(addr) {
+			return ErrURLBlocked
+		}
+	}
+	return nil
+}
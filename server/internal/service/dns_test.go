@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+)
+
+// fakeResolver answers lookups from a fixed table; unknown hosts fail like
+// NXDOMAIN.
+type fakeResolver map[string][]string
+
+func (f fakeResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	answers, ok := f[host]
+	if !ok {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	addrs := make([]netip.Addr, 0, len(answers))
+	for _, answer := range answers {
+		addrs = append(addrs, netip.MustParseAddr(answer))
+	}
+	return addrs, nil
+}
+
+func TestValidateAndNormalizeURLDNSCheck(t *testing.T) {
+	resolver := fakeResolver{
+		"public.example":   {"93.184.216.34"},
+		"loopback.example": {"127.0.0.1"},
+		"private.example":  {"10.1.2.3"},
+		"mixed.example":    {"93.184.216.34", "192.168.0.10"},
+		"v6local.example":  {"::1"},
+		"empty.example":    {},
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		url     string
+		wantErr error
+	}{
+		{"off ignores resolved addresses", DNSCheckOff, "https://loopback.example", nil},
+		{"off ignores unresolvable hosts", DNSCheckOff, "https://missing.example", nil},
+		{"off still blocks literal loopback", DNSCheckOff, "http://127.0.0.1/", ErrURLBlocked},
+		{"off still blocks literal private IPv6", DNSCheckOff, "http://[fd00::1]/", ErrURLBlocked},
+		{"block allows public answers", DNSCheckBlock, "https://public.example", nil},
+		{"block rejects loopback answers", DNSCheckBlock, "https://loopback.example", ErrURLBlocked},
+		{"block rejects private answers", DNSCheckBlock, "https://private.example", ErrURLBlocked},
+		{"block rejects mixed answers", DNSCheckBlock, "https://mixed.example", ErrURLBlocked},
+		{"block rejects IPv6 loopback answers", DNSCheckBlock, "https://v6local.example", ErrURLBlocked},
+		{"block accepts unresolvable hosts", DNSCheckBlock, "https://missing.example", nil},
+		{"strict allows public answers", DNSCheckStrict, "https://public.example", nil},
+		{"strict rejects private answers", DNSCheckStrict, "https://private.example", ErrURLBlocked},
+		{"strict rejects unresolvable hosts", DNSCheckStrict, "https://missing.example", ErrUnresolvableHost},
+		{"strict rejects empty answers", DNSCheckStrict, "https://empty.example", ErrUnresolvableHost},
+		{"strict skips lookups for literal public IPs", DNSCheckStrict, "https://93.184.216.34", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewURLService(nil, nil, nil, URLConfig{DNSCheck: tt.mode, Resolver: resolver})
+
+			_, err := s.validateAndNormalizeURL(context.Background(), tt.url)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("validateAndNormalizeURL(%q) error = %v, want %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
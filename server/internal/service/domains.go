@@ -0,0 +1,280 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net/netip"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/idna"
+)
+
+// DomainBlockedError is returned for destinations whose host is refused by
+// the domain rules. It matches ErrURLBlocked with errors.Is.
+type DomainBlockedError struct {
+	Host string
+	// Rule is the block rule the host matched as written in the rules
+	// file, or "" when the host matched none of the allow rules.
+	Rule string
+}
+
+func (e *DomainBlockedError) Error() string {
+	if e.Rule == "" {
+		return fmt.Sprintf("%v: %s is not on the allowlist", ErrURLBlocked, e.Host)
+	}
+	return fmt.Sprintf("%v: %s matches %q", ErrURLBlocked, e.Host, e.Rule)
+}
+
+func (e *DomainBlockedError) Unwrap() error {
+	return ErrURLBlocked
+}
+
+// domainRule is one line of a domain rules file.
+type domainRule struct {
+	text   string
+	allow  bool
+	exact  string
+	suffix string
+	regex  *regexp.Regexp
+}
+
+func (r domainRule) matches(host string) bool {
+	switch {
+	case r.regex != nil:
+		return r.regex.MatchString(host)
+	case r.suffix != "":
+		return strings.HasSuffix(host, r.suffix)
+	default:
+		return host == r.exact
+	}
+}
+
+// domainRuleSet is the parsed content of a domain rules file.
+type domainRuleSet struct {
+	block []domainRule
+	allow []domainRule
+}
+
+// DomainRules decides which destination hosts links may point to, from a
+// file of allow and block rules. The file is polled for changes and reloaded
+// in place; a file that fails to load is ignored and the previous rules stay
+// in use.
+//
+// Each line holds an action, allow or block, and a pattern: an exact host,
+// *.example.com for any subdomain of example.com, or regex: followed by a
+// regular expression the whole host must match. Hosts and patterns are
+// compared in their punycode form, which regular expressions must use too.
+// Blank lines and lines starting with # are ignored. Block rules win over
+// allow rules, and when there are allow rules a host must match one of them.
+type DomainRules struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	rules   *domainRuleSet
+	modTime time.Time
+	size    int64
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewDomainRules loads the rules file at path and checks it for changes
+// every reloadInterval.
+func NewDomainRules(path string, reloadInterval time.Duration) (*DomainRules, error) {
+	if reloadInterval <= 0 {
+		reloadInterval = 10 * time.Second
+	}
+
+	d := &DomainRules{
+		path:     path,
+		interval: reloadInterval,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if _, err := d.reload(); err != nil {
+		return nil, err
+	}
+
+	go d.run()
+	return d, nil
+}
+
+// Check returns a *DomainBlockedError when host may not be linked to, and
+// ErrInvalidURL when host is not a valid IP address or domain name.
+func (d *DomainRules) Check(host string) error {
+	host, err := normalizeDomain(host)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	d.mu.RLock()
+	rules := d.rules
+	d.mu.RUnlock()
+
+	for _, rule := range rules.block {
+		if rule.matches(host) {
+			return &DomainBlockedError{Host: host, Rule: rule.text}
+		}
+	}
+	if len(rules.allow) == 0 {
+		return nil
+	}
+	for _, rule := range rules.allow {
+		if rule.matches(host) {
+			return nil
+		}
+	}
+	return &DomainBlockedError{Host: host}
+}
+
+// Stop halts reloading. Checks keep using the last loaded rules.
+func (d *DomainRules) Stop() {
+	d.stopOnce.Do(func() { close(d.stopChan) })
+	<-d.done
+}
+
+// reload loads the rules file if it changed since the last attempt and
+// reports whether it did. A file that fails to load is not retried until it
+// changes again.
+func (d *DomainRules) reload() (bool, error) {
+	info, err := os.Stat(d.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat domain rules: %w", err)
+	}
+
+	d.mu.RLock()
+	unchanged := d.rules != nil && info.ModTime().Equal(d.modTime) && info.Size() == d.size
+	d.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	rules, err := loadDomainRules(d.path)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.modTime = info.ModTime()
+	d.size = info.Size()
+	if err != nil {
+		return false, err
+	}
+	d.rules = rules
+	return true, nil
+}
+
+func loadDomainRules(path string) (*domainRuleSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read domain rules: %w", err)
+	}
+
+	rules := &domainRuleSet{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		rule, err := parseDomainRule(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid domain rule on line %d of %s: %w", line, path, err)
+		}
+		if rule.allow {
+			rules.allow = append(rules.allow, rule)
+		} else {
+			rules.block = append(rules.block, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read domain rules: %w", err)
+	}
+	return rules, nil
+}
+
+func parseDomainRule(text string) (domainRule, error) {
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return domainRule{}, fmt.Errorf("%q must be an action followed by a pattern", text)
+	}
+	action, pattern := fields[0], fields[1]
+
+	rule := domainRule{text: action + " " + pattern}
+	switch action {
+	case "allow":
+		rule.allow = true
+	case "block":
+	default:
+		return domainRule{}, fmt.Errorf("action must be allow or block, got %q", action)
+	}
+
+	if expr, ok := strings.CutPrefix(pattern, "regex:"); ok {
+		regex, err := regexp.Compile("^(?:" + expr + ")$")
+		if err != nil {
+			return domainRule{}, err
+		}
+		rule.regex = regex
+		return rule, nil
+	}
+
+	host, wildcard := strings.CutPrefix(pattern, "*.")
+	host, err := normalizeDomain(host)
+	if err != nil {
+		return domainRule{}, fmt.Errorf("%q is not a host, *.host or regex: pattern: %v", pattern, err)
+	}
+	if wildcard {
+		rule.suffix = "." + host
+	} else {
+		rule.exact = host
+	}
+	return rule, nil
+}
+
+// normalizeDomain returns host in the form rules are matched against: IP
+// addresses as they are, domain names lower-cased in their ASCII (punycode)
+// form without a trailing dot, so Unicode and punycode spellings of the same
+// name match the same rules.
+func normalizeDomain(host string) (string, error) {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" {
+		return "", fmt.Errorf("empty host")
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.String(), nil
+	}
+	ascii, err := idna.Lookup.ToASCII(host)
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(ascii), nil
+}
+
+func (d *DomainRules) run() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reloaded, err := d.reload()
+			if err != nil {
+				log.Printf("domain rules: %v", err)
+			}
+			if reloaded {
+				log.Printf("domain rules: reloaded %s", d.path)
+			}
+		case <-d.stopChan:
+			return
+		}
+	}
+}
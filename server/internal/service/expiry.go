@@ -0,0 +1,119 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+)
+
+// Actions applied to links once they expire.
+const (
+	ExpiredActionArchive = "archive"
+	ExpiredActionPurge   = "purge"
+)
+
+// expirySweepBatch bounds how many expired links one ListExpired call returns.
+const expirySweepBatch = 100
+
+// ExpirySweeper periodically archives or purges expired links. Expired links
+// stop redirecting as soon as they expire; the sweeper only cleans up storage.
+type ExpirySweeper struct {
+	repo      repository.URLRepository
+	analytics repository.AnalyticsRepository
+	action    string
+	interval  time.Duration
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewExpirySweeper creates an ExpirySweeper that runs every interval.
+// Purged links take their analytics with them.
+func NewExpirySweeper(repo repository.URLRepository, analytics repository.AnalyticsRepository, action string, interval time.Duration) *ExpirySweeper {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if action == "" {
+		action = ExpiredActionArchive
+	}
+
+	s := &ExpirySweeper{
+		repo:      repo,
+		analytics: analytics,
+		action:    action,
+		interval:  interval,
+		stopChan:  make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// Sweep archives or purges every link that expired before now and returns
+// how many were processed. It stops at the first storage error.
+func (s *ExpirySweeper) Sweep(ctx context.Context) (int, error) {
+	processed := 0
+	for {
+		expired, err := s.repo.ListExpired(ctx, time.Now().UTC(), expirySweepBatch)
+		if err != nil {
+			return processed, fmt.Errorf("failed to list expired URLs: %w", err)
+		}
+
+		for _, url := range expired {
+			if err := s.apply(ctx, url.ID); err != nil {
+				return processed, fmt.Errorf("failed to %s URL %s: %w", s.action, url.ShortCode, err)
+			}
+			processed++
+		}
+
+		if len(expired) < expirySweepBatch {
+			return processed, nil
+		}
+	}
+}
+
+// Stop halts the periodic sweep.
+func (s *ExpirySweeper) Stop() {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	<-s.done
+}
+
+func (s *ExpirySweeper) apply(ctx context.Context, urlID string) error {
+	if s.action == ExpiredActionPurge {
+		// Analytics go first so a failure leaves the link to retry, and a
+		// reused short code does not inherit the old link's clicks.
+		if err := s.analytics.DeleteByURLID(ctx, urlID); err != nil {
+			return fmt.Errorf("failed to purge analytics: %w", err)
+		}
+		return s.repo.Delete(ctx, urlID)
+	}
+	return s.repo.Archive(ctx, urlID, time.Now().UTC())
+}
+
+func (s *ExpirySweeper) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			processed, err := s.Sweep(context.Background())
+			if processed > 0 {
+				log.Printf("expiry sweeper: applied %s to %d expired links", s.action, processed)
+			}
+			if err != nil {
+				log.Printf("expiry sweeper: %v", err)
+			}
+		case <-s.stopChan:
+			return
+		}
+	}
+}
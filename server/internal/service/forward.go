@@ -0,0 +1,78 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+// forwardedPath returns the part of a request path after the short code
+// without its leading slash, or "" when there is none.
+func forwardedPath(requestPath string) string {
+	return strings.TrimPrefix(requestPath, "/")
+}
+
+// destination returns where a visit to link is sent, starting from base,
+// the link's OriginalURL or the destination of a matching rule. Prefix links
+// append requestPath to the destination path, resolving dot segments so the result
+// stays below the destination path. Links forwarding the query add the
+// request parameters the destination does not set itself; the destination's
+// own parameters are kept unchanged and take precedence.
+func destination(link *model.URL, base, requestPath string, query url.Values) (string, error) {
+	rest := forwardedPath(requestPath)
+	forwardPath := link.ForwardPath && rest != ""
+	forwardQuery := link.ForwardQuery && len(query) > 0
+	if !forwardPath && !forwardQuery {
+		return base, nil
+	}
+
+	dest, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse destination: %w", err)
+	}
+
+	if forwardPath {
+		cleaned := strings.TrimPrefix(path.Clean("/"+rest), "/")
+		segments := strings.Split(cleaned, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		suffix := strings.Join(segments, "/")
+		if strings.HasSuffix(rest, "/") && suffix != "" {
+			suffix += "/"
+		}
+		if suffix != "" {
+			dest = dest.JoinPath(suffix)
+		}
+	}
+
+	if forwardQuery {
+		existing := dest.Query()
+		extra := url.Values{}
+		for key, values := range query {
+			if _, ok := existing[key]; !ok {
+				extra[key] = values
+			}
+		}
+		appendQuery(dest, extra)
+	}
+
+	return dest.String(), nil
+}
+
+// appendQuery adds values to the query of dest, leaving the existing query
+// string untouched.
+func appendQuery(dest *url.URL, values url.Values) {
+	encoded := values.Encode()
+	if encoded == "" {
+		return
+	}
+	if dest.RawQuery != "" {
+		dest.RawQuery += "&"
+	}
+	dest.RawQuery += encoded
+}
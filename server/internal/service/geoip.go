@@ -0,0 +1,153 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// geoIPRecord holds the fields read from a MaxMind country or city database.
+type geoIPRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// GeoIP looks up the country of IP addresses in a local MaxMind-format
+// database. The file is polled for changes and reloaded in place; a file that
+// fails to load is ignored and the previous database stays in use.
+type GeoIP struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewGeoIP loads the database at path and checks it for changes every
+// reloadInterval.
+func NewGeoIP(path string, reloadInterval time.Duration) (*GeoIP, error) {
+	if reloadInterval <= 0 {
+		reloadInterval = time.Minute
+	}
+
+	g := &GeoIP{
+		path:     path,
+		interval: reloadInterval,
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if _, err := g.reload(); err != nil {
+		return nil, err
+	}
+
+	go g.run()
+	return g, nil
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country ip is located
+// in, or "" when it is unknown.
+func (g *GeoIP) Country(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	var record geoIPRecord
+	g.mu.RLock()
+	err := g.reader.Lookup(parsed, &record)
+	g.mu.RUnlock()
+	if err != nil {
+		return ""
+	}
+
+	if record.Country.ISOCode != "" {
+		return record.Country.ISOCode
+	}
+	return record.RegisteredCountry.ISOCode
+}
+
+// Stop halts reloading. Lookups keep using the last loaded database.
+func (g *GeoIP) Stop() {
+	g.stopOnce.Do(func() { close(g.stopChan) })
+	<-g.done
+}
+
+// reload loads the database file if it changed since the last attempt and
+// reports whether it did. A file that fails to load is not retried until it
+// changes again. The file is read into memory rather than mapped, so it can
+// be replaced while in use.
+func (g *GeoIP) reload() (bool, error) {
+	info, err := os.Stat(g.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat GeoIP database: %w", err)
+	}
+
+	g.mu.RLock()
+	unchanged := g.reader != nil && info.ModTime().Equal(g.modTime) && info.Size() == g.size
+	g.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	reader, err := openGeoIPDatabase(g.path)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.modTime = info.ModTime()
+	g.size = info.Size()
+	if err != nil {
+		return false, err
+	}
+	g.reader = reader
+	return true, nil
+}
+
+func openGeoIPDatabase(path string) (*maxminddb.Reader, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GeoIP database: %w", err)
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GeoIP database: %w", err)
+	}
+	return reader, nil
+}
+
+func (g *GeoIP) run() {
+	defer close(g.done)
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			reloaded, err := g.reload()
+			if err != nil {
+				log.Printf("geoip: %v", err)
+			}
+			if reloaded {
+				log.Printf("geoip: reloaded %s", g.path)
+			}
+		case <-g.stopChan:
+			return
+		}
+	}
+}
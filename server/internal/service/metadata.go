@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
-	"time"
 )
 
 // LinkPreview contains Open Graph metadata for a URL.
@@ -26,22 +25,14 @@ type MetadataService struct {
 	client *http.Client
 }
 
-// NewMetadataService creates a new MetadataService.
-func NewMetadataService() *MetadataService {
-	return &MetadataService{
-		client: &http.Client{
-			Timeout: 10 * time.Second,
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				if len(via) >= 3 {
-					return fmt.Errorf("too many redirects")
-				}
-				return nil
-			},
-		},
-	}
+// NewMetadataService creates a MetadataService that fetches pages with
+// client, normally one from NewOutboundClient.
+func NewMetadataService(client *http.Client) *MetadataService {
+	return &MetadataService{client: client}
 }
 
-// FetchPreview fetches Open Graph metadata for a URL.
+// FetchPreview fetches Open Graph metadata for a URL. Targets refused by the
+// client's outbound policy return an error wrapping ErrBlockedTarget.
 func (s *MetadataService) FetchPreview(ctx context.Context, targetURL string) (*LinkPreview, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
 	if err != nil {
@@ -71,7 +62,7 @@ func (s *MetadataService) FetchPreview(ctx context.Context, targetURL string) (*
 		URL: targetURL,
 	}
 
-	preview.Title = extractMeta(html, `og:title`) 
+	preview.Title = extractMeta(html, `og:title`)
 	if preview.Title == "" {
 		preview.Title = extractTitle(html)
 	}
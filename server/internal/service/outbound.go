@@ -0,0 +1,171 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedTarget is returned, wrapped, when an outbound request targets a
+// scheme, port or address the outbound policy does not allow.
+var ErrBlockedTarget = errors.New("target is not allowed")
+
+// maxOutboundRedirects bounds the redirects an outbound request follows.
+const maxOutboundRedirects = 3
+
+// blockedNetworks lists special-purpose ranges that outbound requests must
+// not reach, in addition to loopback, private, link-local, multicast and
+// unspecified addresses.
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("192.88.99.0/24"),  // 6to4 relay anycast
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may map to private IPv4
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // local-use NAT64
+	netip.MustParsePrefix("100::/64"),        // discard
+	netip.MustParsePrefix("2001::/23"),       // IETF protocol assignments
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+	netip.MustParsePrefix("2002::/16"),       // 6to4, may embed private IPv4
+	netip.MustParsePrefix("fec0::/10"),       // deprecated site-local
+}
+
+// IsBlockedIP reports whether outbound requests must not connect to ip.
+func IsBlockedIP(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsUnspecified() || ip.IsLoopback() || ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() {
+		return true
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// OutboundPolicy restricts the targets of outbound HTTP requests.
+type OutboundPolicy struct {
+	// AllowedSchemes are the URL schemes requests may use.
+	AllowedSchemes []string
+	// AllowedPorts are the TCP ports requests may connect to; a URL without
+	// a port uses the default port of its scheme.
+	AllowedPorts []int
+	// Timeout bounds a whole request, including redirects.
+	Timeout time.Duration
+
+	// exempt is an address that may be dialled although it is blocked, so
+	// tests can serve the first hop of a request from a local server.
+	exempt netip.AddrPort
+}
+
+// NewOutboundClient returns an HTTP client for requests to user-supplied
+// URLs. The scheme and port of every request, including each redirect, are
+// checked against policy, and every address is checked when it is dialled,
+// after DNS resolution, so a hostname cannot resolve to a blocked address.
+// Proxies from the environment are ignored since they would hide the
+// target address from the check.
+func NewOutboundClient(policy OutboundPolicy) *http.Client {
+	if len(policy.AllowedSchemes) == 0 {
+		policy.AllowedSchemes = []string{"http", "https"}
+	}
+	if len(policy.AllowedPorts) == 0 {
+		policy.AllowedPorts = []int{80, 443}
+	}
+	if policy.Timeout <= 0 {
+		policy.Timeout = 10 * time.Second
+	}
+
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			return policy.checkAddress(address)
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 5 * time.Second,
+	}
+
+	return &http.Client{
+		Timeout:   policy.Timeout,
+		Transport: &outboundTransport{next: transport, policy: policy},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxOutboundRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxOutboundRedirects)
+			}
+			return nil
+		},
+	}
+}
+
+// outboundTransport checks the scheme and port of each request, including
+// every redirect, before passing it on.
+type outboundTransport struct {
+	next   http.RoundTripper
+	policy OutboundPolicy
+}
+
+func (t *outboundTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.policy.checkURL(req); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+func (p OutboundPolicy) checkURL(req *http.Request) error {
+	scheme := strings.ToLower(req.URL.Scheme)
+	if !slices.Contains(p.AllowedSchemes, scheme) {
+		return fmt.Errorf("%w: scheme %q", ErrBlockedTarget, scheme)
+	}
+
+	port := req.URL.Port()
+	if port == "" {
+		switch scheme {
+		case "http":
+			port = "80"
+		case "https":
+			port = "443"
+		}
+	}
+	if n, err := strconv.Atoi(port); err != nil || !slices.Contains(p.AllowedPorts, n) {
+		return fmt.Errorf("%w: port %q", ErrBlockedTarget, port)
+	}
+	return nil
+}
+
+// checkAddress checks the resolved "ip:port" a connection is about to use.
+func (p OutboundPolicy) checkAddress(address string) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: address %q", ErrBlockedTarget, address)
+	}
+	if addrPort != p.exempt && IsBlockedIP(addrPort.Addr()) {
+		return fmt.Errorf("%w: address %s", ErrBlockedTarget, addrPort.Addr())
+	}
+	if !slices.Contains(p.AllowedPorts, int(addrPort.Port())) {
+		return fmt.Errorf("%w: port %d", ErrBlockedTarget, addrPort.Port())
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// localServer starts an httptest server and returns it with its address.
+func localServer(t *testing.T, handler http.Handler) (*httptest.Server, netip.AddrPort) {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	addr, err := netip.ParseAddrPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to parse server address: %v", err)
+	}
+	return server, addr
+}
+
+// previewService returns a MetadataService whose client may dial exempt and
+// connect to the given ports.
+func previewService(exempt netip.AddrPort, ports ...int) *MetadataService {
+	return NewMetadataService(NewOutboundClient(OutboundPolicy{
+		AllowedPorts: ports,
+		exempt:       exempt,
+	}))
+}
+
+func TestFetchPreviewBlocksLoopbackAtDialTime(t *testing.T) {
+	var hits atomic.Int32
+	server, addr := localServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	service := previewService(netip.AddrPort{}, int(addr.Port()))
+
+	for _, target := range []string{
+		server.URL,
+		"http://localhost:" + strconv.Itoa(int(addr.Port())),
+	} {
+		_, err := service.FetchPreview(context.Background(), target)
+		if !errors.Is(err, ErrBlockedTarget) {
+			t.Errorf("FetchPreview(%q) error = %v, want ErrBlockedTarget", target, err)
+		}
+	}
+	if n := hits.Load(); n != 0 {
+		t.Fatalf("blocked server received %d requests", n)
+	}
+}
+
+func TestFetchPreviewChecksRedirects(t *testing.T) {
+	var targetHits atomic.Int32
+	target, targetAddr := localServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetHits.Add(1)
+	}))
+
+	tests := []struct {
+		name     string
+		location string
+	}{
+		{"loopback address", target.URL + "/internal"},
+		{"port outside the allowlist", "http://example.invalid:8081/"},
+		{"scheme outside the allowlist", "ftp://example.invalid/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, frontAddr := localServer(t, http.RedirectHandler(tt.location, http.StatusFound))
+			service := previewService(frontAddr, int(frontAddr.Port()), int(targetAddr.Port()))
+
+			_, err := service.FetchPreview(context.Background(), "http://"+frontAddr.String()+"/")
+			if !errors.Is(err, ErrBlockedTarget) {
+				t.Fatalf("FetchPreview error = %v, want ErrBlockedTarget", err)
+			}
+		})
+	}
+	if n := targetHits.Load(); n != 0 {
+		t.Fatalf("redirect target received %d requests", n)
+	}
+}
+
+func TestFetchPreviewAllowsExemptServer(t *testing.T) {
+	_, addr := localServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><meta property="og:title" content="Hello"></head></html>`))
+	}))
+	service := previewService(addr, int(addr.Port()))
+
+	preview, err := service.FetchPreview(context.Background(), "http://"+addr.String()+"/")
+	if err != nil {
+		t.Fatalf("FetchPreview error = %v", err)
+	}
+	if preview.Title != "Hello" {
+		t.Fatalf("preview title = %q, want %q", preview.Title, "Hello")
+	}
+}
+
+func TestOutboundClientIgnoresProxyEnvironment(t *testing.T) {
+	client := NewOutboundClient(OutboundPolicy{})
+
+	outbound, ok := client.Transport.(*outboundTransport)
+	if !ok {
+		t.Fatalf("transport is %T, want *outboundTransport", client.Transport)
+	}
+	transport, ok := outbound.next.(*http.Transport)
+	if !ok {
+		t.Fatalf("inner transport is %T, want *http.Transport", outbound.next)
+	}
+	if transport.Proxy != nil {
+		t.Fatal("outbound transport uses a proxy")
+	}
+}
@@ -0,0 +1,47 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"strings"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+// platformTokens maps User-Agent substrings to platforms. Order matters:
+// iOS user agents contain "like Mac OS X" and Android ones "Linux".
+var platformTokens = []struct {
+	token    string
+	platform string
+}{
+	{"iPhone", model.PlatformIOS},
+	{"iPad", model.PlatformIOS},
+	{"iPod", model.PlatformIOS},
+	{"Android", model.PlatformAndroid},
+	{"Windows", model.PlatformWindows},
+	{"Macintosh", model.PlatformMacOS},
+	{"Mac OS X", model.PlatformMacOS},
+	{"Linux", model.PlatformLinux},
+	{"X11", model.PlatformLinux},
+}
+
+// PlatformFromUserAgent returns the platform a User-Agent header belongs to,
+// or model.PlatformOther when it is not recognised.
+func PlatformFromUserAgent(userAgent string) string {
+	for _, t := range platformTokens {
+		if strings.Contains(userAgent, t.token) {
+			return t.platform
+		}
+	}
+	return model.PlatformOther
+}
+
+// isPlatform reports whether platform is one a rule can match.
+func isPlatform(platform string) bool {
+	switch platform {
+	case model.PlatformIOS, model.PlatformAndroid, model.PlatformWindows,
+		model.PlatformMacOS, model.PlatformLinux, model.PlatformOther:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,234 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+// maxRulesPerURL bounds the targeting rules of one link, which are evaluated
+// on every redirect.
+const maxRulesPerURL = 20
+
+var (
+	ErrRuleNotFound     = errors.New("rule not found")
+	ErrTooManyRules     = errors.New("a link can have at most 20 rules")
+	ErrInvalidPlatform  = errors.New("platforms must be ios, android, windows, macos, linux or other")
+	ErrInvalidPosition  = errors.New("position is out of range")
+	ErrInvalidCountry   = errors.New("countries must be ISO 3166-1 alpha-2 codes")
+	ErrGeoIPUnavailable = errors.New("country rules require a GeoIP database")
+)
+
+// visit holds the properties of a redirect request that rules match on.
+type visit struct {
+	platform string
+	// country is empty when unknown; it then matches no country rule.
+	country string
+	// at is the time of the visit in the link's time zone.
+	at time.Time
+}
+
+// matchRule returns the first rule matching v, or nil.
+func matchRule(rules []model.RedirectRule, v visit) *model.RedirectRule {
+	for i := range rules {
+		rule := &rules[i]
+		if len(rule.Platforms) > 0 && !slices.Contains(rule.Platforms, v.platform) {
+			continue
+		}
+		if len(rule.Countries) > 0 && !slices.Contains(rule.Countries, v.country) {
+			continue
+		}
+		if !inWindow(rule, v.at) {
+			continue
+		}
+		return rule
+	}
+	return nil
+}
+
+// usesCountry reports whether any rule matches on country, so the visitor's
+// country only has to be looked up when it is.
+func usesCountry(rules []model.RedirectRule) bool {
+	return slices.ContainsFunc(rules, func(rule model.RedirectRule) bool {
+		return len(rule.Countries) > 0
+	})
+}
+
+// Rules returns the targeting rules of the URL with shortCode in evaluation
+// order.
+func (s *URLService) Rules(ctx context.Context, shortCode string) ([]model.RedirectRule, error) {
+	url, err := s.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if url.Rules == nil {
+		return []model.RedirectRule{}, nil
+	}
+	return url.Rules, nil
+}
+
+// AddRule adds a targeting rule to the URL with shortCode.
+func (s *URLService) AddRule(ctx context.Context, shortCode string, input model.RedirectRuleInput, actor string) (*model.RedirectRule, error) {
+	rule, err := s.buildRule(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var added model.RedirectRule
+	_, err = s.modify(ctx, shortCode, input.Version, actor, func(url *model.URL, now time.Time) ([]string, error) {
+		if len(url.Rules) >= maxRulesPerURL {
+			return nil, ErrTooManyRules
+		}
+		position := len(url.Rules)
+		if input.Position != nil {
+			if *input.Position < 0 || *input.Position > len(url.Rules) {
+				return nil, ErrInvalidPosition
+			}
+			position = *input.Position
+		}
+
+		id, err := newRuleID()
+		if err != nil {
+			return nil, err
+		}
+		added = rule
+		added.ID = id
+		added.CreatedAt = now
+		added.UpdatedAt = now
+		url.Rules = slices.Insert(slices.Clone(url.Rules), position, added)
+		return []string{"rules"}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &added, nil
+}
+
+// UpdateRule replaces the rule with ruleID of the URL with shortCode.
+func (s *URLService) UpdateRule(ctx context.Context, shortCode, ruleID string, input model.RedirectRuleInput, actor string) (*model.RedirectRule, error) {
+	rule, err := s.buildRule(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated model.RedirectRule
+	_, err = s.modify(ctx, shortCode, input.Version, actor, func(url *model.URL, now time.Time) ([]string, error) {
+		index := slices.IndexFunc(url.Rules, func(r model.RedirectRule) bool { return r.ID == ruleID })
+		if index < 0 {
+			return nil, ErrRuleNotFound
+		}
+
+		updated = rule
+		updated.ID = ruleID
+		updated.CreatedAt = url.Rules[index].CreatedAt
+		updated.UpdatedAt = now
+
+		rules := slices.Delete(slices.Clone(url.Rules), index, index+1)
+		position := index
+		if input.Position != nil {
+			if *input.Position < 0 || *input.Position > len(rules) {
+				return nil, ErrInvalidPosition
+			}
+			position = *input.Position
+		}
+		url.Rules = slices.Insert(rules, position, updated)
+		return []string{"rules"}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// DeleteRule removes the rule with ruleID from the URL with shortCode.
+func (s *URLService) DeleteRule(ctx context.Context, shortCode, ruleID, actor string) error {
+	_, err := s.modify(ctx, shortCode, nil, actor, func(url *model.URL, now time.Time) ([]string, error) {
+		index := slices.IndexFunc(url.Rules, func(r model.RedirectRule) bool { return r.ID == ruleID })
+		if index < 0 {
+			return nil, ErrRuleNotFound
+		}
+		url.Rules = slices.Delete(slices.Clone(url.Rules), index, index+1)
+		return []string{"rules"}, nil
+	})
+	return err
+}
+
+// buildRule validates input and returns the rule it describes, with
+// platforms and days lower-cased, countries upper-cased, all deduplicated,
+// and the destination normalized.
+func (s *URLService) buildRule(ctx context.Context, input model.RedirectRuleInput) (model.RedirectRule, error) {
+	destination, err := s.validateAndNormalizeURL(ctx, input.Destination)
+	if err != nil {
+		return model.RedirectRule{}, err
+	}
+
+	var platforms []string
+	for _, platform := range input.Platforms {
+		platform = strings.ToLower(strings.TrimSpace(platform))
+		if !isPlatform(platform) {
+			return model.RedirectRule{}, ErrInvalidPlatform
+		}
+		if !slices.Contains(platforms, platform) {
+			platforms = append(platforms, platform)
+		}
+	}
+
+	var countries []string
+	for _, country := range input.Countries {
+		country = strings.ToUpper(strings.TrimSpace(country))
+		if !isCountryCode(country) {
+			return model.RedirectRule{}, ErrInvalidCountry
+		}
+		if !slices.Contains(countries, country) {
+			countries = append(countries, country)
+		}
+	}
+	if len(countries) > 0 && s.geo == nil {
+		return model.RedirectRule{}, ErrGeoIPUnavailable
+	}
+
+	days, from, until, err := buildSchedule(input)
+	if err != nil {
+		return model.RedirectRule{}, err
+	}
+
+	return model.RedirectRule{
+		Platforms:   platforms,
+		Countries:   countries,
+		Days:        days,
+		From:        from,
+		Until:       until,
+		Destination: destination,
+	}, nil
+}
+
+// isCountryCode reports whether code looks like an upper-case ISO 3166-1
+// alpha-2 code.
+func isCountryCode(code string) bool {
+	if len(code) != 2 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRuleID returns a random identifier for a rule.
+func newRuleID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate rule ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
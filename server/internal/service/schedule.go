@@ -0,0 +1,134 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+// clockLayout is the format of the from and until times of a rule.
+const clockLayout = "15:04"
+
+var (
+	ErrURLNotActive       = errors.New("URL is not active yet")
+	ErrInvalidActiveFrom  = errors.New("activeFrom must be before the link expires")
+	ErrActiveFromConflict = errors.New("activeFrom and removeActiveFrom are mutually exclusive")
+	ErrInvalidTimezone    = errors.New("timezone must be an IANA time zone such as Europe/Berlin")
+	ErrInvalidSchedule    = errors.New("days must be mon to sun, and from and until distinct HH:MM times given together")
+)
+
+// weekdays maps time.Weekday to the day names of rules.
+var weekdays = [...]string{
+	time.Sunday:    model.DaySunday,
+	time.Monday:    model.DayMonday,
+	time.Tuesday:   model.DayTuesday,
+	time.Wednesday: model.DayWednesday,
+	time.Thursday:  model.DayThursday,
+	time.Friday:    model.DayFriday,
+	time.Saturday:  model.DaySaturday,
+}
+
+// locations caches loaded time zones, which time.LoadLocation reads from
+// disk on every call.
+var locations sync.Map
+
+// loadLocation returns the IANA time zone name, where "" is UTC. "Local" is
+// rejected since it depends on the server.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if cached, ok := locations.Load(name); ok {
+		return cached.(*time.Location), nil
+	}
+	if name == "Local" {
+		return nil, ErrInvalidTimezone
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimezone
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// linkTime returns now in the time zone of url, falling back to UTC when
+// the zone is no longer known.
+func linkTime(url *model.URL, now time.Time) time.Time {
+	loc, err := loadLocation(url.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return now.In(loc)
+}
+
+// checkActiveFrom reports whether the activation of url precedes its expiry.
+func checkActiveFrom(url *model.URL) error {
+	if url.ActiveFrom != nil && url.ExpiresAt != nil && !url.ActiveFrom.Before(*url.ExpiresAt) {
+		return ErrInvalidActiveFrom
+	}
+	return nil
+}
+
+// inWindow reports whether the local time at falls in the time window of
+// rule. Rules without days or times are always in their window.
+func inWindow(rule *model.RedirectRule, at time.Time) bool {
+	day := at.Weekday()
+	if rule.From != "" {
+		from, _ := clockMinutes(rule.From)
+		until, _ := clockMinutes(rule.Until)
+		minute := at.Hour()*60 + at.Minute()
+
+		switch {
+		case from < until:
+			if minute < from || minute >= until {
+				return false
+			}
+		case minute < until:
+			// The part after midnight of a window started the day before.
+			day = (day + 6) % 7
+		case minute < from:
+			return false
+		}
+	}
+	return len(rule.Days) == 0 || slices.Contains(rule.Days, weekdays[day])
+}
+
+// buildSchedule validates the time window of input and returns its days,
+// lower-cased and deduplicated, and its from and until times.
+func buildSchedule(input model.RedirectRuleInput) (days []string, from, until string, err error) {
+	for _, day := range input.Days {
+		day = strings.ToLower(strings.TrimSpace(day))
+		if !slices.Contains(weekdays[:], day) {
+			return nil, "", "", ErrInvalidSchedule
+		}
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+
+	if input.From == "" && input.Until == "" {
+		return days, "", "", nil
+	}
+	fromMinutes, fromErr := clockMinutes(input.From)
+	untilMinutes, untilErr := clockMinutes(input.Until)
+	if fromErr != nil || untilErr != nil || fromMinutes == untilMinutes {
+		return nil, "", "", ErrInvalidSchedule
+	}
+	return days, input.From, input.Until, nil
+}
+
+// clockMinutes parses an "HH:MM" time into minutes after midnight.
+func clockMinutes(clock string) (int, error) {
+	t, err := time.Parse(clockLayout, clock)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,317 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+)
+
+var ErrSpoolFull = errors.New("analytics spool is full")
+
+// SpoolConfig configures the analytics spool.
+type SpoolConfig struct {
+	Path            string
+	MaxBytes        int64
+	ReplayInterval  time.Duration
+	ReplayBatchSize int
+}
+
+// SpoolStats reports the state of the analytics spool.
+type SpoolStats struct {
+	Bytes    int64 `json:"bytes"`
+	Spooled  int64 `json:"spooled"`
+	Replayed int64 `json:"replayed"`
+	Dropped  int64 `json:"dropped"`
+}
+
+// AnalyticsSpool keeps click events that could not be written to the
+// repository in an append-only JSON lines file and replays them in the
+// background once writes succeed again.
+//
+// New events are appended to Path. A replay first moves Path aside to
+// Path+".replay" so that appends continue on a fresh file, then writes the
+// moved events in batches. Events that still fail are left in the replay
+// file for the next attempt.
+type AnalyticsSpool struct {
+	repo   repository.AnalyticsRepository
+	config SpoolConfig
+
+	mu          sync.Mutex
+	file        *os.File
+	size        int64
+	replaySize  int64
+	replayMutex sync.Mutex
+
+	spooled  atomic.Int64
+	replayed atomic.Int64
+	dropped  atomic.Int64
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewAnalyticsSpool opens the spool file and starts the replay loop.
+func NewAnalyticsSpool(repo repository.AnalyticsRepository, cfg SpoolConfig) (*AnalyticsSpool, error) {
+	if cfg.ReplayInterval <= 0 {
+		cfg.ReplayInterval = 30 * time.Second
+	}
+	if cfg.ReplayBatchSize <= 0 {
+		cfg.ReplayBatchSize = 100
+	}
+
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open analytics spool: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat analytics spool: %w", err)
+	}
+
+	s := &AnalyticsSpool{
+		repo:     repo,
+		config:   cfg,
+		file:     file,
+		size:     info.Size(),
+		stopChan: make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if info, err := os.Stat(s.replayPath()); err == nil {
+		s.replaySize = info.Size()
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Append writes entries to the spool. Entries that would push the spool past
+// MaxBytes are dropped and ErrSpoolFull is returned.
+func (s *AnalyticsSpool) Append(entries []model.AnalyticsEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var buf []byte
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode analytics entry: %w", err)
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.MaxBytes > 0 && s.size+s.replaySize+int64(len(buf)) > s.config.MaxBytes {
+		s.dropped.Add(int64(len(entries)))
+		return ErrSpoolFull
+	}
+
+	n, err := s.file.Write(buf)
+	s.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write analytics spool: %w", err)
+	}
+
+	s.spooled.Add(int64(len(entries)))
+	return nil
+}
+
+// Replay writes spooled events to the repository, stopping at the first
+// failure.
+func (s *AnalyticsSpool) Replay(ctx context.Context) error {
+	s.replayMutex.Lock()
+	defer s.replayMutex.Unlock()
+
+	if err := s.rotate(); err != nil {
+		return err
+	}
+
+	entries, err := s.readReplayFile()
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(entries); start += s.config.ReplayBatchSize {
+		end := min(start+s.config.ReplayBatchSize, len(entries))
+
+		written, err := s.repo.CreateBatch(ctx, entries[start:end])
+		s.replayed.Add(int64(written))
+		if err != nil {
+			if rewriteErr := s.rewriteReplayFile(entries[start+written:]); rewriteErr != nil {
+				return errors.Join(err, rewriteErr)
+			}
+			return err
+		}
+	}
+
+	return s.rewriteReplayFile(nil)
+}
+
+// Close stops the replay loop and closes the spool file.
+func (s *AnalyticsSpool) Close() error {
+	s.stopOnce.Do(func() { close(s.stopChan) })
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// Stats returns spool counters.
+func (s *AnalyticsSpool) Stats() SpoolStats {
+	s.mu.Lock()
+	bytes := s.size + s.replaySize
+	s.mu.Unlock()
+
+	return SpoolStats{
+		Bytes:    bytes,
+		Spooled:  s.spooled.Load(),
+		Replayed: s.replayed.Load(),
+		Dropped:  s.dropped.Load(),
+	}
+}
+
+func (s *AnalyticsSpool) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.config.ReplayInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Replay(context.Background()); err != nil {
+				log.Printf("analytics spool: replay failed: %v", err)
+			}
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+func (s *AnalyticsSpool) replayPath() string {
+	return s.config.Path + ".replay"
+}
+
+// rotate moves the active spool file to the replay path unless a previous
+// replay file is still pending.
+func (s *AnalyticsSpool) rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.replaySize > 0 || s.size == 0 {
+		return nil
+	}
+
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to close analytics spool: %w", err)
+	}
+	if err := os.Rename(s.config.Path, s.replayPath()); err != nil {
+		return fmt.Errorf("failed to rotate analytics spool: %w", err)
+	}
+
+	file, err := os.OpenFile(s.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to reopen analytics spool: %w", err)
+	}
+
+	s.file = file
+	s.replaySize = s.size
+	s.size = 0
+	return nil
+}
+
+func (s *AnalyticsSpool) readReplayFile() ([]model.AnalyticsEntry, error) {
+	file, err := os.Open(s.replayPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool replay file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []model.AnalyticsEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry model.AnalyticsEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			s.dropped.Add(1)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read spool replay file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// rewriteReplayFile replaces the replay file with the given remaining
+// entries, removing it when none remain.
+func (s *AnalyticsSpool) rewriteReplayFile(remaining []model.AnalyticsEntry) error {
+	if len(remaining) == 0 {
+		if err := os.Remove(s.replayPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove spool replay file: %w", err)
+		}
+		s.mu.Lock()
+		s.replaySize = 0
+		s.mu.Unlock()
+		return nil
+	}
+
+	tmpPath := s.replayPath() + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite spool replay file: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range remaining {
+		if err := encoder.Encode(entry); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to rewrite spool replay file: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to rewrite spool replay file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to rewrite spool replay file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to rewrite spool replay file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.replayPath()); err != nil {
+		return fmt.Errorf("failed to rewrite spool replay file: %w", err)
+	}
+
+	s.mu.Lock()
+	s.replaySize = info.Size()
+	s.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+)
+
+var errRepoDown = errors.New("repository unavailable")
+
+// budgetRepo accepts budget more entries and then fails with errRepoDown; a
+// negative budget accepts everything. Written entries are identified by
+// their UserAgent.
+type budgetRepo struct {
+	repository.AnalyticsRepository
+	budget  int
+	written []string
+}
+
+func (r *budgetRepo) CreateBatch(_ context.Context, entries []model.AnalyticsEntry) (int, error) {
+	for i, entry := range entries {
+		if r.budget == 0 {
+			return i, errRepoDown
+		}
+		if r.budget > 0 {
+			r.budget--
+		}
+		r.written = append(r.written, entry.UserAgent)
+	}
+	return len(entries), nil
+}
+
+// spoolTime is fixed so that every encoded entry has the same length.
+var spoolTime = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// spoolEntries returns entries whose UserAgents are the given names.
+func spoolEntries(names ...string) []model.AnalyticsEntry {
+	entries := make([]model.AnalyticsEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, model.AnalyticsEntry{URLId: "url", UserAgent: name, Timestamp: spoolTime})
+	}
+	return entries
+}
+
+// spooledNames returns the UserAgents of the entries in the file at path.
+func spooledNames(t *testing.T, path string) []string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry model.AnalyticsEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid line in %s: %v", path, err)
+		}
+		names = append(names, entry.UserAgent)
+	}
+	return names
+}
+
+func newTestSpool(t *testing.T, repo repository.AnalyticsRepository, cfg SpoolConfig) *AnalyticsSpool {
+	t.Helper()
+
+	cfg.Path = filepath.Join(t.TempDir(), "spool.jsonl")
+	cfg.ReplayInterval = time.Hour
+	spool, err := NewAnalyticsSpool(repo, cfg)
+	if err != nil {
+		t.Fatalf("NewAnalyticsSpool error = %v", err)
+	}
+	t.Cleanup(func() { spool.Close() })
+	return spool
+}
+
+func TestSpoolReplayRotatesFile(t *testing.T) {
+	repo := &budgetRepo{}
+	spool := newTestSpool(t, repo, SpoolConfig{})
+	path := spool.config.Path
+
+	if err := spool.Append(spoolEntries("a", "b")); err != nil {
+		t.Fatalf("Append error = %v", err)
+	}
+	if err := spool.Replay(context.Background()); !errors.Is(err, errRepoDown) {
+		t.Fatalf("Replay error = %v, want errRepoDown", err)
+	}
+	if err := spool.Append(spoolEntries("c")); err != nil {
+		t.Fatalf("Append error = %v", err)
+	}
+
+	if got := spooledNames(t, path+".replay"); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("replay file holds %v, want [a b]", got)
+	}
+	if got := spooledNames(t, path); !slices.Equal(got, []string{"c"}) {
+		t.Errorf("spool file holds %v, want [c]", got)
+	}
+
+	repo.budget = -1
+	for range 2 {
+		if err := spool.Replay(context.Background()); err != nil {
+			t.Fatalf("Replay error = %v", err)
+		}
+	}
+	if !slices.Equal(repo.written, []string{"a", "b", "c"}) {
+		t.Errorf("replayed %v, want [a b c]", repo.written)
+	}
+	if _, err := os.Stat(path + ".replay"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("replay file still exists: %v", err)
+	}
+	if stats := spool.Stats(); stats.Bytes != 0 || stats.Spooled != 3 || stats.Replayed != 3 {
+		t.Errorf("stats = %+v, want 0 bytes, 3 spooled and 3 replayed", stats)
+	}
+}
+
+func TestSpoolReplayRewritesRemainder(t *testing.T) {
+	repo := &budgetRepo{budget: 3}
+	spool := newTestSpool(t, repo, SpoolConfig{ReplayBatchSize: 2})
+	path := spool.config.Path
+
+	if err := spool.Append(spoolEntries("a", "b", "c", "d", "e")); err != nil {
+		t.Fatalf("Append error = %v", err)
+	}
+	if err := spool.Replay(context.Background()); !errors.Is(err, errRepoDown) {
+		t.Fatalf("Replay error = %v, want errRepoDown", err)
+	}
+	if !slices.Equal(repo.written, []string{"a", "b", "c"}) {
+		t.Fatalf("replayed %v, want [a b c]", repo.written)
+	}
+	if got := spooledNames(t, path+".replay"); !slices.Equal(got, []string{"d", "e"}) {
+		t.Fatalf("replay file holds %v, want the unwritten [d e]", got)
+	}
+
+	repo.budget = -1
+	if err := spool.Replay(context.Background()); err != nil {
+		t.Fatalf("Replay error = %v", err)
+	}
+	if !slices.Equal(repo.written, []string{"a", "b", "c", "d", "e"}) {
+		t.Fatalf("replayed %v, want each entry once", repo.written)
+	}
+}
+
+func TestSpoolDropsPastMaxBytes(t *testing.T) {
+	entry, err := json.Marshal(spoolEntries("a")[0])
+	if err != nil {
+		t.Fatalf("failed to encode entry: %v", err)
+	}
+	spool := newTestSpool(t, &budgetRepo{}, SpoolConfig{MaxBytes: int64(len(entry)+1) * 2})
+
+	for i := range 3 {
+		err := spool.Append(spoolEntries(fmt.Sprint(i)))
+		if i < 2 && err != nil {
+			t.Fatalf("Append %d error = %v", i, err)
+		}
+		if i == 2 && !errors.Is(err, ErrSpoolFull) {
+			t.Fatalf("Append past MaxBytes error = %v, want ErrSpoolFull", err)
+		}
+	}
+	if stats := spool.Stats(); stats.Spooled != 2 || stats.Dropped != 1 {
+		t.Fatalf("stats = %+v, want 2 spooled and 1 dropped", stats)
+	}
+}
@@ -0,0 +1,149 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+)
+
+// trashPurgeBatch bounds how many trashed links one ListTrashedBefore call
+// returns.
+const trashPurgeBatch = 100
+
+// ListTrash retrieves paginated trashed URLs, most recently deleted first.
+func (s *URLService) ListTrash(ctx context.Context, limit, offset int) (*model.URLListResponse, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	urls, total, err := s.repo.ListTrash(ctx, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch trashed URLs: %w", err)
+	}
+
+	return &model.URLListResponse{
+		URLs:   urls,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// Restore takes the URL with shortCode out of the trash. URLs that are not
+// in the trash return repository.ErrURLNotFound.
+func (s *URLService) Restore(ctx context.Context, shortCode string) (*model.URL, error) {
+	if shortCode == "" {
+		return nil, ErrShortCodeEmpty
+	}
+
+	url, err := s.repo.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if url.DeletedAt == nil {
+		return nil, repository.ErrURLNotFound
+	}
+
+	if err := s.repo.Restore(ctx, url.ID, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	return s.GetByShortCode(ctx, shortCode)
+}
+
+// TrashPurger periodically deletes links, with their analytics, once they
+// have been in the trash for the retention period.
+type TrashPurger struct {
+	urls      repository.URLRepository
+	analytics repository.AnalyticsRepository
+	retention time.Duration
+	interval  time.Duration
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	done     chan struct{}
+}
+
+// NewTrashPurger creates a TrashPurger that runs every interval.
+func NewTrashPurger(urls repository.URLRepository, analytics repository.AnalyticsRepository, retention, interval time.Duration) *TrashPurger {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+
+	p := &TrashPurger{
+		urls:      urls,
+		analytics: analytics,
+		retention: max(retention, 0),
+		interval:  interval,
+		stopChan:  make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Purge deletes every link trashed longer than the retention period ago and
+// returns how many were deleted. It stops at the first storage error.
+func (p *TrashPurger) Purge(ctx context.Context) (int, error) {
+	purged := 0
+	for {
+		trashed, err := p.urls.ListTrashedBefore(ctx, time.Now().UTC().Add(-p.retention), trashPurgeBatch)
+		if err != nil {
+			return purged, fmt.Errorf("failed to list trashed URLs: %w", err)
+		}
+
+		for _, url := range trashed {
+			// Analytics go first so a failure leaves the link to retry.
+			if err := p.analytics.DeleteByURLID(ctx, url.ID); err != nil {
+				return purged, fmt.Errorf("failed to purge analytics of URL %s: %w", url.ShortCode, err)
+			}
+			if err := p.urls.Delete(ctx, url.ID); err != nil {
+				return purged, fmt.Errorf("failed to purge URL %s: %w", url.ShortCode, err)
+			}
+			purged++
+		}
+
+		if len(trashed) < trashPurgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+// Stop halts the periodic purge.
+func (p *TrashPurger) Stop() {
+	p.stopOnce.Do(func() { close(p.stopChan) })
+	<-p.done
+}
+
+func (p *TrashPurger) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := p.Purge(context.Background())
+			if purged > 0 {
+				log.Printf("trash purger: deleted %d links", purged)
+			}
+			if err != nil {
+				log.Printf("trash purger: %v", err)
+			}
+		case <-p.stopChan:
+			return
+		}
+	}
+}
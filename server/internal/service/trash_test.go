@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+)
+
+// createURL stores a link with shortCode and returns it with its ID set.
+func createURL(t *testing.T, repo repository.URLRepository, url model.URL) *model.URL {
+	t.Helper()
+
+	url.OriginalURL = "https://example.com/" + url.ShortCode
+	url.CreatedAt = time.Now().UTC()
+	url.UpdatedAt = url.CreatedAt
+	id, err := repo.Create(context.Background(), url)
+	if err != nil {
+		t.Fatalf("failed to create %s: %v", url.ShortCode, err)
+	}
+	url.ID = id
+	return &url
+}
+
+// countAnalytics returns how many analytics entries urlID has.
+func countAnalytics(t *testing.T, repo repository.AnalyticsRepository, urlID string) int {
+	t.Helper()
+
+	entries, err := repo.GetByURLID(context.Background(), urlID, 100, 0)
+	if err != nil {
+		t.Fatalf("failed to list analytics: %v", err)
+	}
+	return len(entries)
+}
+
+func TestPurgeDropsQueuedClicks(t *testing.T) {
+	ctx := context.Background()
+	urls := repository.NewMemoryURLRepository()
+	analytics := repository.NewMemoryAnalyticsRepository(urls)
+
+	purged := createURL(t, urls, model.URL{ShortCode: "gone"})
+	kept := createURL(t, urls, model.URL{ShortCode: "kept"})
+
+	service := NewAnalyticsService(analytics, nil, AnalyticsConfig{FlushInterval: time.Hour})
+	req := httptest.NewRequest("GET", "/", nil)
+	for _, url := range []*model.URL{purged, purged, kept} {
+		if err := service.RecordClick(ctx, url, "", req); err != nil {
+			t.Fatalf("failed to record click: %v", err)
+		}
+	}
+
+	if err := urls.Trash(ctx, purged.ID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("failed to trash URL: %v", err)
+	}
+	purger := NewTrashPurger(urls, analytics, 0, time.Hour)
+	defer purger.Stop()
+	if n, err := purger.Purge(ctx); err != nil || n != 1 {
+		t.Fatalf("Purge() = %d, %v, want 1, nil", n, err)
+	}
+
+	if err := service.Close(ctx); err != nil {
+		t.Fatalf("failed to close analytics: %v", err)
+	}
+	if n := countAnalytics(t, analytics, purged.ID); n != 0 {
+		t.Errorf("purged URL has %d analytics entries, want 0", n)
+	}
+	if n := countAnalytics(t, analytics, kept.ID); n != 1 {
+		t.Errorf("kept URL has %d analytics entries, want 1", n)
+	}
+	if stats := service.Stats(); stats.Failed != 0 {
+		t.Errorf("%d events failed to write, want 0", stats.Failed)
+	}
+}
+
+func TestPurgeDropsSpooledClicks(t *testing.T) {
+	ctx := context.Background()
+	urls := repository.NewMemoryURLRepository()
+	analytics := repository.NewMemoryAnalyticsRepository(urls)
+
+	expiredAt := time.Now().Add(-time.Minute).UTC()
+	purged := createURL(t, urls, model.URL{ShortCode: "gone", ExpiresAt: &expiredAt})
+	kept := createURL(t, urls, model.URL{ShortCode: "kept"})
+
+	path := filepath.Join(t.TempDir(), "spool.jsonl")
+	spool, err := NewAnalyticsSpool(analytics, SpoolConfig{Path: path, ReplayInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("failed to open spool: %v", err)
+	}
+	defer spool.Close()
+
+	now := time.Now().UTC()
+	if err := spool.Append([]model.AnalyticsEntry{
+		{URLId: purged.ID, Timestamp: now},
+		{URLId: kept.ID, Timestamp: now},
+		{URLId: purged.ID, Timestamp: now},
+	}); err != nil {
+		t.Fatalf("failed to spool entries: %v", err)
+	}
+
+	sweeper := NewExpirySweeper(urls, analytics, ExpiredActionPurge, time.Hour)
+	defer sweeper.Stop()
+	if n, err := sweeper.Sweep(ctx); err != nil || n != 1 {
+		t.Fatalf("Sweep() = %d, %v, want 1, nil", n, err)
+	}
+
+	if err := spool.Replay(ctx); err != nil {
+		t.Fatalf("Replay() error = %v", err)
+	}
+	if _, err := os.Stat(path + ".replay"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("replay file still exists after replay: %v", err)
+	}
+	if n := countAnalytics(t, analytics, purged.ID); n != 0 {
+		t.Errorf("purged URL has %d analytics entries, want 0", n)
+	}
+	if n := countAnalytics(t, analytics, kept.ID); n != 1 {
+		t.Errorf("kept URL has %d analytics entries, want 1", n)
+	}
+}
@@ -7,7 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
-	"net"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
@@ -15,15 +15,31 @@ import (
 
 	"github.com/abhisheksharm-3/shrtn/internal/model"
 	"github.com/abhisheksharm-3/shrtn/internal/repository"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrShortCodeExists   = errors.New("short code already in use")
-	ErrInvalidURL        = errors.New("invalid URL format")
-	ErrShortCodeEmpty    = errors.New("short code cannot be empty")
-	ErrShortCodeTooShort = errors.New("short code must be at least 3 characters")
-	ErrShortCodeInvalid  = errors.New("short code contains invalid characters")
-	ErrURLBlocked        = errors.New("URL is not allowed")
+	ErrShortCodeExists      = errors.New("short code already in use")
+	ErrInvalidURL           = errors.New("invalid URL format")
+	ErrShortCodeEmpty       = errors.New("short code cannot be empty")
+	ErrShortCodeTooShort    = errors.New("short code must be at least 3 characters")
+	ErrShortCodeInvalid     = errors.New("short code contains invalid characters")
+	ErrURLBlocked           = errors.New("URL is not allowed")
+	ErrShortCodeUnavailable = errors.New("could not allocate a unique short code")
+	ErrInvalidExpiry        = errors.New("expiration must be a future time or a positive duration")
+	ErrExpiryConflict       = errors.New("expiresAt and ttl are mutually exclusive")
+	ErrURLExpired           = errors.New("URL has expired")
+	ErrURLDisabled          = errors.New("URL is disabled")
+	ErrInvalidMaxClicks     = errors.New("maxClicks must not be negative")
+	ErrURLExhausted         = errors.New("URL has reached its click limit")
+	ErrInvalidPassword      = errors.New("password must be at most 72 bytes")
+	ErrPasswordRequired     = errors.New("URL is password protected")
+	ErrPasswordIncorrect    = errors.New("incorrect password")
+	ErrNoChanges            = errors.New("no changes requested")
+	ErrVersionConflict      = errors.New("URL was modified by another request")
+	ErrInvalidRedirect      = errors.New("redirectStatus must be 301, 302, 307 or 308")
+	ErrInvalidUTM           = errors.New("utm values must be at most 256 characters")
 )
 
 const (
@@ -31,6 +47,13 @@ const (
 	shortCodeLength  = 6
 	minCustomLength  = 3
 	maxCustomLength  = 20
+
+	// maxGenerateAttempts bounds how many random codes Create tries before
+	// giving up on collisions.
+	maxGenerateAttempts = 5
+
+	// maxPasswordLength is the longest password bcrypt accepts.
+	maxPasswordLength = 72
 )
 
 var (
@@ -42,67 +65,522 @@ var (
 	}
 )
 
+// URLConfig configures URLService.
+type URLConfig struct {
+	// DefaultRedirectStatus is used for links created without a redirect
+	// status.
+	DefaultRedirectStatus int
+	// RedirectCacheMaxAge bounds how long clients may cache permanent
+	// redirects.
+	RedirectCacheMaxAge time.Duration
+
+	// DNSCheck is DNSCheckOff, DNSCheckBlock or DNSCheckStrict. Lookups
+	// use Resolver, or the system resolver when it is nil, and are bounded
+	// by DNSTimeout.
+	DNSCheck   string
+	DNSTimeout time.Duration
+	Resolver   Resolver
+
+	// Domains restricts the hosts links may point to, both when they are
+	// saved and when they redirect. nil allows any host.
+	Domains *DomainRules
+}
+
 // URLService handles business logic for URL shortening.
 type URLService struct {
-	repo repository.URLRepository
+	repo   repository.URLRepository
+	clicks *ClickCounter
+	geo    *GeoIP
+	config URLConfig
 }
 
-// NewURLService creates a new URLService with the given repository.
-func NewURLService(repo repository.URLRepository) *URLService {
-	return &URLService{repo: repo}
+// NewURLService creates a new URLService with the given repository and click
+// counter. geo may be nil, which disables country rules.
+func NewURLService(repo repository.URLRepository, clicks *ClickCounter, geo *GeoIP, cfg URLConfig) *URLService {
+	if !IsRedirectStatus(cfg.DefaultRedirectStatus) {
+		cfg.DefaultRedirectStatus = http.StatusFound
+	}
+	return &URLService{repo: repo, clicks: clicks, geo: geo, config: cfg}
+}
+
+// IsRedirectStatus reports whether status is a redirect status a link may use.
+func IsRedirectStatus(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	default:
+		return false
+	}
 }
 
 // Create creates a new shortened URL.
 func (s *URLService) Create(ctx context.Context, input model.URLInput) (*model.URL, error) {
-	normalizedURL, err := s.validateAndNormalizeURL(input.OriginalURL)
+	normalizedURL, err := s.validateAndNormalizeURL(ctx, input.OriginalURL)
 	if err != nil {
 		return nil, err
 	}
 
-	shortCode := input.CustomCode
-	if shortCode == "" {
-		shortCode, err = s.generateShortCode()
-		if err != nil {
-			return nil, fmt.Errorf("failed to generate short code: %w", err)
-		}
-	} else {
-		if err := s.validateCustomCode(shortCode); err != nil {
-			return nil, err
-		}
+	if input.MaxClicks < 0 {
+		return nil, ErrInvalidMaxClicks
+	}
+	if len(input.Password) > maxPasswordLength {
+		return nil, ErrInvalidPassword
+	}
+	redirectStatus := input.RedirectStatus
+	if redirectStatus == 0 {
+		redirectStatus = s.config.DefaultRedirectStatus
+	}
+	if !IsRedirectStatus(redirectStatus) {
+		return nil, ErrInvalidRedirect
 	}
 
-	existingURL, err := s.repo.GetByShortCode(ctx, shortCode)
-	if err == nil && existingURL != nil {
-		return nil, ErrShortCodeExists
+	utm, err := normalizeUTM(input.UTM)
+	if err != nil {
+		return nil, err
 	}
-	if err != nil && !errors.Is(err, repository.ErrURLNotFound) {
-		return nil, fmt.Errorf("error checking short code availability: %w", err)
+	normalizedURL, err = applyUTM(normalizedURL, utm)
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.normalizeVariants(ctx, input.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := loadLocation(input.Timezone); err != nil {
+		return nil, err
 	}
 
 	now := time.Now().UTC()
+	expiresAt, err := resolveExpiry(input, now)
+	if err != nil {
+		return nil, err
+	}
+
 	newURL := model.URL{
-		ShortCode:   shortCode,
 		OriginalURL: normalizedURL,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 		Clicks:      0,
+		Version:     1,
+		ExpiresAt:   expiresAt,
+		ActiveFrom:  utcPtr(input.ActiveFrom),
+		Timezone:    input.Timezone,
+		MaxClicks:   input.MaxClicks,
+
+		RedirectStatus: redirectStatus,
+		ForwardQuery:   input.ForwardQuery,
+		ForwardPath:    input.ForwardPath,
+		UTM:            utm,
+		Variants:       variants,
+	}
+	if err := checkActiveFrom(&newURL); err != nil {
+		return nil, err
 	}
 
-	id, err := s.repo.Create(ctx, newURL)
+	newURL.PasswordHash, err = hashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.CustomCode != "" {
+		if err := s.validateCustomCode(input.CustomCode); err != nil {
+			return nil, err
+		}
+		newURL.ShortCode = input.CustomCode
+		return s.insert(ctx, newURL)
+	}
+
+	for attempt := 0; attempt < maxGenerateAttempts; attempt++ {
+		newURL.ShortCode, err = s.generateShortCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate short code: %w", err)
+		}
+
+		created, err := s.insert(ctx, newURL)
+		if errors.Is(err, ErrShortCodeExists) {
+			continue
+		}
+		return created, err
+	}
+
+	return nil, ErrShortCodeUnavailable
+}
+
+// insert stores url, mapping a storage-level short code collision to
+// ErrShortCodeExists.
+func (s *URLService) insert(ctx context.Context, url model.URL) (*model.URL, error) {
+	id, err := s.repo.Create(ctx, url)
+	if errors.Is(err, repository.ErrDuplicateShortCode) {
+		return nil, ErrShortCodeExists
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create URL: %w", err)
 	}
 
-	newURL.ID = id
-	return &newURL, nil
+	url.ID = id
+	return &url, nil
 }
 
-// GetByShortCode retrieves a URL by its short code.
+// GetByShortCode retrieves a URL by its short code. Trashed URLs are not
+// found.
 func (s *URLService) GetByShortCode(ctx context.Context, shortCode string) (*model.URL, error) {
 	if shortCode == "" {
 		return nil, ErrShortCodeEmpty
 	}
-	return s.repo.GetByShortCode(ctx, shortCode)
+
+	url, err := s.repo.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if url.DeletedAt != nil {
+		return nil, repository.ErrURLNotFound
+	}
+
+	if url.RedirectStatus == 0 {
+		url.RedirectStatus = s.config.DefaultRedirectStatus
+	}
+	setRemainingClicks(url)
+	return url, nil
+}
+
+// ResolveRequest describes a visit to a short link.
+type ResolveRequest struct {
+	ShortCode string
+	// Password is the password submitted for a protected link, if any.
+	Password string
+	// Path is the request path after the short code, such as "/a/b" for
+	// "/docs/a/b". Only prefix links accept a non-empty path.
+	Path string
+	// Query is the query string of the request.
+	Query url.Values
+	// UserAgent is the User-Agent header, matched by platform rules.
+	UserAgent string
+	// ClientIP is the visitor's address, located for country rules and
+	// hashed with UserAgent to assign A/B variants.
+	ClientIP string
+	// Variant is the variant the visitor was assigned on an earlier visit.
+	Variant string
+}
+
+// Resolution is the result of resolving a visit to a short link.
+type Resolution struct {
+	URL *model.URL
+	// Destination is the URL the visitor is redirected to, including any
+	// forwarded path and query.
+	Destination string
+	// Variant is the ID of the A/B variant served, if any.
+	Variant string
+}
+
+// Resolve retrieves the URL a visit to a short link should lead to, taking
+// the first matching targeting rule, then an A/B variant, over OriginalURL.
+// It returns ErrURLDisabled for disabled links, ErrURLExpired once the link
+// is past its expiry or archived, and ErrURLNotActive before it goes live.
+// Password-protected links return ErrPasswordRequired when no password is
+// given and ErrPasswordIncorrect when it does not match, before any of the
+// errors above. A path on a link
+// that is not a prefix link returns repository.ErrURLNotFound, and a
+// destination on a domain the domain rules block a *DomainBlockedError.
+// Clicks on click-limited links are counted here, atomically, and
+// ErrURLExhausted is returned once the limit is reached; callers must not
+// count those clicks again through IncrementClicks.
+func (s *URLService) Resolve(ctx context.Context, req ResolveRequest) (*Resolution, error) {
+	url, err := s.GetByShortCode(ctx, req.ShortCode)
+	if err != nil {
+		return nil, err
+	}
+	if forwardedPath(req.Path) != "" && !url.ForwardPath {
+		return nil, repository.ErrURLNotFound
+	}
+	// The password comes first so visitors without it learn nothing about
+	// whether the link is disabled, expired or scheduled.
+	if url.PasswordHash != "" {
+		if req.Password == "" {
+			return nil, ErrPasswordRequired
+		}
+		if bcrypt.CompareHashAndPassword([]byte(url.PasswordHash), []byte(req.Password)) != nil {
+			return nil, ErrPasswordIncorrect
+		}
+	}
+
+	if url.Disabled {
+		return nil, ErrURLDisabled
+	}
+	now := time.Now()
+	if url.IsExpired(now) {
+		return nil, ErrURLExpired
+	}
+	if !url.IsActive(now) {
+		return nil, ErrURLNotActive
+	}
+
+	base := url.OriginalURL
+	v := visit{platform: PlatformFromUserAgent(req.UserAgent), at: linkTime(url, now)}
+	if s.geo != nil && usesCountry(url.Rules) {
+		v.country = s.geo.Country(req.ClientIP)
+	}
+	var variantID string
+	if rule := matchRule(url.Rules, v); rule != nil {
+		base = rule.Destination
+	} else if variant := pickVariant(url.Variants, req.Variant, req.ShortCode+"|"+req.ClientIP+"|"+req.UserAgent); variant != nil {
+		base = variant.Destination
+		variantID = variant.ID
+	}
+	dest, err := destination(url, base, req.Path, req.Query)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkDestination(dest); err != nil {
+		return nil, err
+	}
+
+	if url.MaxClicks > 0 {
+		remaining, err := s.repo.ConsumeClick(ctx, url.ID)
+		if errors.Is(err, repository.ErrClickLimitReached) {
+			return nil, ErrURLExhausted
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to count click: %w", err)
+		}
+		url.RemainingClicks = &remaining
+	}
+
+	return &Resolution{URL: url, Destination: dest, Variant: variantID}, nil
+}
+
+// Update applies a partial update to the URL with shortCode and records it
+// in the URL's history under actor.
+func (s *URLService) Update(ctx context.Context, shortCode string, input model.URLUpdate, actor string) (*model.URL, error) {
+	return s.modify(ctx, shortCode, input.Version, actor, func(url *model.URL, now time.Time) ([]string, error) {
+		return s.applyUpdate(ctx, url, input, now)
+	})
+}
+
+// Disable stops the URL with shortCode from redirecting, keeping its code
+// and analytics, and records the change under actor. Disabling a disabled
+// URL changes nothing.
+func (s *URLService) Disable(ctx context.Context, shortCode, actor string) (*model.URL, error) {
+	return s.setDisabled(ctx, shortCode, true, actor)
+}
+
+// Enable makes a disabled URL with shortCode redirect again.
+func (s *URLService) Enable(ctx context.Context, shortCode, actor string) (*model.URL, error) {
+	return s.setDisabled(ctx, shortCode, false, actor)
+}
+
+func (s *URLService) setDisabled(ctx context.Context, shortCode string, disabled bool, actor string) (*model.URL, error) {
+	url, err := s.modify(ctx, shortCode, nil, actor, func(url *model.URL, now time.Time) ([]string, error) {
+		if url.Disabled == disabled {
+			return nil, nil
+		}
+		url.Disabled = disabled
+		return []string{"disabled"}, nil
+	})
+	if errors.Is(err, ErrNoChanges) {
+		return s.GetByShortCode(ctx, shortCode)
+	}
+	return url, err
+}
+
+// modify stores the result of edit as the next version of the URL with
+// shortCode and records it in the URL's history under actor. edit changes
+// url in place and returns the names of the fields it changed. When version
+// is set it must match the stored version; otherwise a conflicting
+// concurrent update is retried once against a fresh read.
+func (s *URLService) modify(
+	ctx context.Context,
+	shortCode string,
+	version *int,
+	actor string,
+	edit func(url *model.URL, now time.Time) ([]string, error),
+) (*model.URL, error) {
+	for attempt := 0; ; attempt++ {
+		url, err := s.GetByShortCode(ctx, shortCode)
+		if err != nil {
+			return nil, err
+		}
+		if version != nil && *version != url.Version {
+			return nil, ErrVersionConflict
+		}
+
+		previousURL := url.OriginalURL
+		now := time.Now().UTC()
+		fields, err := edit(url, now)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) == 0 {
+			return nil, ErrNoChanges
+		}
+
+		url.Version++
+		url.UpdatedAt = now
+		change := model.URLChange{
+			URLId:       url.ID,
+			Version:     url.Version,
+			PreviousURL: previousURL,
+			OriginalURL: url.OriginalURL,
+			Fields:      fields,
+			Actor:       actor,
+			ChangedAt:   now,
+		}
+
+		err = s.repo.Update(ctx, *url, change)
+		if errors.Is(err, repository.ErrVersionConflict) {
+			if version == nil && attempt == 0 {
+				continue
+			}
+			return nil, ErrVersionConflict
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update URL: %w", err)
+		}
+
+		setRemainingClicks(url)
+		return url, nil
+	}
+}
+
+// History returns the recorded changes of the URL with shortCode, newest
+// first.
+func (s *URLService) History(ctx context.Context, shortCode string) ([]model.URLChange, error) {
+	url, err := s.GetByShortCode(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.repo.ListHistory(ctx, url.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL history: %w", err)
+	}
+	return changes, nil
+}
+
+// applyUpdate validates input and applies it to url, returning the names of
+// the changed fields.
+func (s *URLService) applyUpdate(ctx context.Context, url *model.URL, input model.URLUpdate, now time.Time) ([]string, error) {
+	var fields []string
+
+	if input.OriginalURL != nil {
+		normalized, err := s.validateAndNormalizeURL(ctx, *input.OriginalURL)
+		if err != nil {
+			return nil, err
+		}
+		// Keep the link's campaign tagging on the new destination.
+		normalized, err = applyUTM(normalized, url.UTM)
+		if err != nil {
+			return nil, err
+		}
+		url.OriginalURL = normalized
+		fields = append(fields, "originalUrl")
+	}
+
+	if input.RemoveExpiry || input.ExpiresAt != nil || input.TTL != nil {
+		expiry := model.URLInput{ExpiresAt: input.ExpiresAt}
+		if input.TTL != nil {
+			expiry.TTL = *input.TTL
+		}
+		if input.RemoveExpiry && (expiry.ExpiresAt != nil || expiry.TTL != "") {
+			return nil, ErrExpiryConflict
+		}
+
+		expiresAt, err := resolveExpiry(expiry, now)
+		if err != nil {
+			return nil, err
+		}
+		url.ExpiresAt = expiresAt
+		url.ArchivedAt = nil
+		fields = append(fields, "expiresAt")
+	}
+
+	if input.RemoveActiveFrom || input.ActiveFrom != nil {
+		if input.RemoveActiveFrom && input.ActiveFrom != nil {
+			return nil, ErrActiveFromConflict
+		}
+		url.ActiveFrom = utcPtr(input.ActiveFrom)
+		fields = append(fields, "activeFrom")
+	}
+	if err := checkActiveFrom(url); err != nil {
+		return nil, err
+	}
+
+	if input.Timezone != nil {
+		if _, err := loadLocation(*input.Timezone); err != nil {
+			return nil, err
+		}
+		url.Timezone = *input.Timezone
+		fields = append(fields, "timezone")
+	}
+
+	if input.MaxClicks != nil {
+		if *input.MaxClicks < 0 {
+			return nil, ErrInvalidMaxClicks
+		}
+		url.MaxClicks = *input.MaxClicks
+		fields = append(fields, "maxClicks")
+	}
+
+	if input.Password != nil {
+		if len(*input.Password) > maxPasswordLength {
+			return nil, ErrInvalidPassword
+		}
+		hash, err := hashPassword(*input.Password)
+		if err != nil {
+			return nil, err
+		}
+		url.PasswordHash = hash
+		fields = append(fields, "password")
+	}
+
+	if input.RedirectStatus != nil {
+		if !IsRedirectStatus(*input.RedirectStatus) {
+			return nil, ErrInvalidRedirect
+		}
+		url.RedirectStatus = *input.RedirectStatus
+		fields = append(fields, "redirectStatus")
+	}
+
+	if input.ForwardQuery != nil {
+		url.ForwardQuery = *input.ForwardQuery
+		fields = append(fields, "forwardQuery")
+	}
+	if input.ForwardPath != nil {
+		url.ForwardPath = *input.ForwardPath
+		fields = append(fields, "forwardPath")
+	}
+
+	if input.Variants != nil {
+		variants, err := s.normalizeVariants(ctx, *input.Variants)
+		if err != nil {
+			return nil, err
+		}
+		url.Variants = variants
+		fields = append(fields, "variants")
+	}
+
+	return fields, nil
+}
+
+// RedirectCacheMaxAge returns how long clients may cache the redirect for
+// url, or 0 when it must not be cached. Only permanent redirects are
+// cacheable, never for click-limited links or links with targeting rules or
+// variants, and never past expiry.
+func (s *URLService) RedirectCacheMaxAge(url *model.URL) time.Duration {
+	if url.RedirectStatus != http.StatusMovedPermanently && url.RedirectStatus != http.StatusPermanentRedirect {
+		return 0
+	}
+	if url.MaxClicks > 0 || url.PasswordHash != "" || len(url.Rules) > 0 || len(url.Variants) > 0 {
+		return 0
+	}
+
+	maxAge := s.config.RedirectCacheMaxAge
+	if url.ExpiresAt != nil {
+		maxAge = min(maxAge, time.Until(*url.ExpiresAt))
+	}
+	return max(maxAge, 0)
 }
 
 // GetAll retrieves paginated URLs.
@@ -130,23 +608,31 @@ func (s *URLService) GetAll(ctx context.Context, limit, offset int) (*model.URLL
 	}, nil
 }
 
-// IncrementClicks updates the click count for a URL.
-func (s *URLService) IncrementClicks(ctx context.Context, urlID string, currentClicks int) error {
+// IncrementClicks queues one click for a URL; counts are persisted in
+// batches by the ClickCounter.
+func (s *URLService) IncrementClicks(urlID string) error {
 	if urlID == "" {
 		return errors.New("URL ID cannot be empty")
 	}
-	return s.repo.UpdateClicks(ctx, urlID, currentClicks)
+	s.clicks.Add(urlID)
+	return nil
+}
+
+// ClickStats reports pending and flushed click counts.
+func (s *URLService) ClickStats() ClickCounterStats {
+	return s.clicks.Stats()
 }
 
-// Delete removes a URL by its ID.
+// Delete moves a URL to the trash by its ID. It stops redirecting at once
+// and keeps its short code until the trash is purged.
 func (s *URLService) Delete(ctx context.Context, urlID string) error {
 	if urlID == "" {
 		return errors.New("URL ID cannot be empty")
 	}
-	return s.repo.Delete(ctx, urlID)
+	return s.repo.Trash(ctx, urlID, time.Now().UTC())
 }
 
-func (s *URLService) validateAndNormalizeURL(inputURL string) (string, error) {
+func (s *URLService) validateAndNormalizeURL(ctx context.Context, inputURL string) (string, error) {
 	if inputURL == "" {
 		return "", ErrInvalidURL
 	}
@@ -172,15 +658,92 @@ func (s *URLService) validateAndNormalizeURL(inputURL string) (string, error) {
 		return "", ErrInvalidURL
 	}
 
-	if ip := net.ParseIP(parsed.Hostname()); ip != nil {
-		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() {
-			return "", ErrURLBlocked
-		}
+	if err := s.checkDomain(parsed.Hostname()); err != nil {
+		return "", err
+	}
+	if err := s.checkHost(ctx, parsed.Hostname()); err != nil {
+		return "", err
 	}
 
 	return normalized, nil
 }
 
+// checkDomain applies the domain rules, if any, to host.
+func (s *URLService) checkDomain(host string) error {
+	if s.config.Domains == nil {
+		return nil
+	}
+	return s.config.Domains.Check(host)
+}
+
+// checkDestination applies the domain rules to the host of a resolved
+// destination, so links to domains blocked after they were saved stop
+// redirecting.
+func (s *URLService) checkDestination(dest string) error {
+	if s.config.Domains == nil {
+		return nil
+	}
+	parsed, err := url.Parse(dest)
+	if err != nil {
+		return ErrInvalidURL
+	}
+	return s.checkDomain(parsed.Hostname())
+}
+
+// setRemainingClicks derives url.RemainingClicks from its click limit.
+func setRemainingClicks(url *model.URL) {
+	url.RemainingClicks = nil
+	if url.MaxClicks > 0 {
+		remaining := max(url.MaxClicks-url.Clicks, 0)
+		url.RemainingClicks = &remaining
+	}
+}
+
+// hashPassword returns the bcrypt hash of password, or "" when it is empty.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// resolveExpiry turns the absolute or relative expiry of input into an
+// absolute time, or nil when the link never expires.
+func resolveExpiry(input model.URLInput, now time.Time) (*time.Time, error) {
+	switch {
+	case input.ExpiresAt != nil && input.TTL != "":
+		return nil, ErrExpiryConflict
+	case input.ExpiresAt != nil:
+		if !input.ExpiresAt.After(now) {
+			return nil, ErrInvalidExpiry
+		}
+		expiresAt := input.ExpiresAt.UTC()
+		return &expiresAt, nil
+	case input.TTL != "":
+		ttl, err := time.ParseDuration(input.TTL)
+		if err != nil || ttl <= 0 {
+			return nil, ErrInvalidExpiry
+		}
+		expiresAt := now.Add(ttl)
+		return &expiresAt, nil
+	default:
+		return nil, nil
+	}
+}
+
+// utcPtr returns a copy of an optional time in UTC.
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
 func (s *URLService) validateCustomCode(code string) error {
 	if len(code) < minCustomLength {
 		return ErrShortCodeTooShort
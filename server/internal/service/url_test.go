@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+	"github.com/abhisheksharm-3/shrtn/internal/repository"
+)
+
+// collidingRepo fails the first collisions Create calls with
+// ErrDuplicateShortCode and records every code it was asked to store.
+type collidingRepo struct {
+	*repository.MemoryURLRepository
+	collisions int
+	codes      []string
+}
+
+func (r *collidingRepo) Create(ctx context.Context, url model.URL) (string, error) {
+	r.codes = append(r.codes, url.ShortCode)
+	if len(r.codes) <= r.collisions {
+		return "", repository.ErrDuplicateShortCode
+	}
+	return r.MemoryURLRepository.Create(ctx, url)
+}
+
+func TestCreateRetriesGeneratedCodes(t *testing.T) {
+	tests := []struct {
+		name       string
+		collisions int
+		customCode string
+		wantErr    error
+		wantTries  int
+	}{
+		{"no collision", 0, "", nil, 1},
+		{"retries until a code is free", maxGenerateAttempts - 1, "", nil, maxGenerateAttempts},
+		{"gives up after the attempt limit", maxGenerateAttempts, "", ErrShortCodeUnavailable, maxGenerateAttempts},
+		{"custom codes are not retried", 1, "mine", ErrShortCodeExists, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &collidingRepo{MemoryURLRepository: repository.NewMemoryURLRepository(), collisions: tt.collisions}
+			s := NewURLService(repo, nil, nil, URLConfig{})
+
+			url, err := s.Create(context.Background(), model.URLInput{
+				OriginalURL: "https://example.com",
+				CustomCode:  tt.customCode,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Create error = %v, want %v", err, tt.wantErr)
+			}
+			if len(repo.codes) != tt.wantTries {
+				t.Fatalf("Create tried %d codes, want %d", len(repo.codes), tt.wantTries)
+			}
+			if err == nil && url.ShortCode != repo.codes[len(repo.codes)-1] {
+				t.Fatalf("created code %q, want the last one tried, %q", url.ShortCode, repo.codes[len(repo.codes)-1])
+			}
+		})
+	}
+}
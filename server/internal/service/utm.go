@@ -0,0 +1,113 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+// maxUTMValueLength bounds each UTM parameter value.
+const maxUTMValueLength = 256
+
+// UTMConflict describes a UTM parameter whose value differs from one the
+// destination URL already sets.
+type UTMConflict struct {
+	Param       string `json:"param"`
+	Destination string `json:"destination"`
+	UTM         string `json:"utm"`
+}
+
+// UTMConflictError is returned when UTM parameters conflict with the query
+// of the destination URL.
+type UTMConflictError struct {
+	Conflicts []UTMConflict
+}
+
+func (e *UTMConflictError) Error() string {
+	params := make([]string, len(e.Conflicts))
+	for i, conflict := range e.Conflicts {
+		params[i] = conflict.Param
+	}
+	return "utm parameters conflict with the destination query: " + strings.Join(params, ", ")
+}
+
+// utmParams returns the query parameter names and values of utm in a
+// fixed order.
+func utmParams(utm model.UTM) [][2]string {
+	return [][2]string{
+		{"utm_source", utm.Source},
+		{"utm_medium", utm.Medium},
+		{"utm_campaign", utm.Campaign},
+		{"utm_term", utm.Term},
+		{"utm_content", utm.Content},
+	}
+}
+
+// normalizeUTM trims the values of utm and returns nil when none is set.
+func normalizeUTM(utm *model.UTM) (*model.UTM, error) {
+	if utm == nil {
+		return nil, nil
+	}
+
+	normalized := model.UTM{
+		Source:   strings.TrimSpace(utm.Source),
+		Medium:   strings.TrimSpace(utm.Medium),
+		Campaign: strings.TrimSpace(utm.Campaign),
+		Term:     strings.TrimSpace(utm.Term),
+		Content:  strings.TrimSpace(utm.Content),
+	}
+	for _, param := range utmParams(normalized) {
+		if len(param[1]) > maxUTMValueLength {
+			return nil, ErrInvalidUTM
+		}
+	}
+	if normalized.IsZero() {
+		return nil, nil
+	}
+	return &normalized, nil
+}
+
+// applyUTM adds the UTM parameters of utm to the query of rawURL. A
+// parameter the URL already sets to the same value is left as it is; any
+// other value is reported in a *UTMConflictError.
+func applyUTM(rawURL string, utm *model.UTM) (string, error) {
+	if utm == nil {
+		return rawURL, nil
+	}
+
+	dest, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse destination: %w", err)
+	}
+
+	existing := dest.Query()
+	extra := url.Values{}
+	var conflicts []UTMConflict
+	for _, param := range utmParams(*utm) {
+		name, value := param[0], param[1]
+		if value == "" {
+			continue
+		}
+		current, ok := existing[name]
+		if !ok {
+			extra.Set(name, value)
+			continue
+		}
+		if len(current) != 1 || current[0] != value {
+			conflicts = append(conflicts, UTMConflict{
+				Param:       name,
+				Destination: strings.Join(current, ","),
+				UTM:         value,
+			})
+		}
+	}
+	if len(conflicts) > 0 {
+		return "", &UTMConflictError{Conflicts: conflicts}
+	}
+
+	appendQuery(dest, extra)
+	return dest.String(), nil
+}
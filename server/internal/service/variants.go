@@ -0,0 +1,88 @@
+// Package service implements business logic for the URL shortener.
+package service
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"regexp"
+	"slices"
+
+	"github.com/abhisheksharm-3/shrtn/internal/model"
+)
+
+const (
+	maxVariantsPerURL = 10
+	maxVariantWeight  = 1000
+)
+
+var (
+	ErrInvalidVariants = errors.New("variants need a destination, a weight between 1 and 1000 and unique IDs of up to 32 letters, digits, '-' or '_'")
+	ErrTooManyVariants = errors.New("a link can have at most 10 variants")
+)
+
+var variantIDRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,32}$`)
+
+// normalizeVariants validates variants and returns them with normalized
+// destinations. Variants without an ID are named "a", "b", ... by position.
+func (s *URLService) normalizeVariants(ctx context.Context, variants []model.Variant) ([]model.Variant, error) {
+	if len(variants) > maxVariantsPerURL {
+		return nil, ErrTooManyVariants
+	}
+
+	normalized := make([]model.Variant, 0, len(variants))
+	for i, variant := range variants {
+		if variant.ID == "" {
+			variant.ID = string(rune('a' + i))
+		}
+		if !variantIDRegex.MatchString(variant.ID) || variant.Weight < 1 || variant.Weight > maxVariantWeight {
+			return nil, ErrInvalidVariants
+		}
+		if slices.ContainsFunc(normalized, func(v model.Variant) bool { return v.ID == variant.ID }) {
+			return nil, ErrInvalidVariants
+		}
+
+		destination, err := s.validateAndNormalizeURL(ctx, variant.Destination)
+		if err != nil {
+			return nil, err
+		}
+		variant.Destination = destination
+		normalized = append(normalized, variant)
+	}
+
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// pickVariant returns the variant a visitor is assigned. The variant named
+// by assigned, typically from a cookie set on an earlier visit, is kept while
+// it exists; otherwise the visitor is placed by weight using a hash of
+// visitorKey, so the same visitor lands on the same variant without one.
+func pickVariant(variants []model.Variant, assigned, visitorKey string) *model.Variant {
+	if len(variants) == 0 {
+		return nil
+	}
+	if assigned != "" {
+		if i := slices.IndexFunc(variants, func(v model.Variant) bool { return v.ID == assigned }); i >= 0 {
+			return &variants[i]
+		}
+	}
+
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(visitorKey))
+	point := int(h.Sum64() % uint64(total))
+	for i := range variants {
+		point -= variants[i].Weight
+		if point < 0 {
+			return &variants[i]
+		}
+	}
+	return &variants[len(variants)-1]
+}